@@ -37,6 +37,32 @@ type Provider interface {
 	Exist(key string) (bool, error)
 }
 
+// BatchProvider is an optional interface for providers which support multi-key operations natively.
+type BatchProvider interface {
+	// GetMulti returns cached values in the same order as keys, invalid items are set to data.Nil.
+	GetMulti(keys ...string) ([]data.Value, error)
+	SetMulti(items map[string]interface{}, expiry time.Duration) error
+	RemoveMulti(keys ...string) error
+}
+
+// TagProvider is an optional interface for providers which support tag-based invalidation.
+type TagProvider interface {
+	// Tag attaches tags to key, expiry is the expiry of the item.
+	Tag(key string, expiry time.Duration, tags ...string) error
+	// RemoveTag removes all items attached with the tag.
+	RemoveTag(tag string) error
+}
+
+// Tags attaches tags to a cache item, it should be passed as an arg of Set.
+//
+//	cache.Set(user, "user", 42, cache.Tags("user:42", "org:7"))
+func Tags(tags ...string) TagList {
+	return tags
+}
+
+// TagList is a list of cache tags.
+type TagList []string
+
 func Register(name string, f func(opts data.Map) (Provider, error)) {
 	providers[name] = f
 }
@@ -67,10 +93,28 @@ func Remove(key string, args ...interface{}) {
 	defaultCacher().Remove(key, args...)
 }
 
+// GetMulti returns cached values of multiple items, each arg identifies an item, the result is assured of not nil.
+func GetMulti(key string, args ...interface{}) []data.Value {
+	return defaultCacher().GetMulti(key, args...)
+}
+
+func SetMulti(values []interface{}, key string, args ...interface{}) {
+	defaultCacher().SetMulti(values, key, args...)
+}
+
+func RemoveMulti(key string, args ...interface{}) {
+	defaultCacher().RemoveMulti(key, args...)
+}
+
 func RemoveGroup(key string) {
 	defaultCacher().RemoveGroup(key)
 }
 
+// RemoveTag removes all items attached with any of the tags.
+func RemoveTag(tags ...string) {
+	defaultCacher().RemoveTag(tags...)
+}
+
 type factory struct {
 	locker  sync.Mutex
 	cachers map[string]Cacher
@@ -101,6 +101,24 @@ func TestCacher(t *testing.T) {
 	assert.True(t, c.Get(key).IsNil())
 }
 
+func TestMulti(t *testing.T) {
+	cache.SetMulti([]interface{}{1, 2}, "test2", 1, 2)
+	assert.True(t, cache.Exist("test2", 1))
+	assert.True(t, cache.Exist("test2", 2))
+
+	values := cache.GetMulti("test2", 1, 2, 3)
+	assert.Equal(t, 3, len(values))
+	assert.True(t, values[2].IsNil())
+
+	actual, err := values[1].Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, actual)
+
+	cache.RemoveMulti("test2", 1, 2)
+	assert.False(t, cache.Exist("test2", 1))
+	assert.False(t, cache.Exist("test2", 2))
+}
+
 func TestRemoveVersion(t *testing.T) {
 	cache.Set(1, "test1")
 	cache.Set(1, "test2", 1)
@@ -111,3 +129,17 @@ func TestRemoveVersion(t *testing.T) {
 	assert.False(t, cache.Exist("test1"))
 	assert.False(t, cache.Exist("test2", 1))
 }
+
+func TestRemoveTag(t *testing.T) {
+	cache.Set(1, "tag", 1, cache.Tags("user:1", "org:1"))
+	cache.Set(2, "tag", 2, cache.Tags("user:2", "org:1"))
+	assert.True(t, cache.Exist("tag", 1))
+	assert.True(t, cache.Exist("tag", 2))
+
+	cache.RemoveTag("user:1")
+	assert.False(t, cache.Exist("tag", 1))
+	assert.True(t, cache.Exist("tag", 2))
+
+	cache.RemoveTag("org:1")
+	assert.False(t, cache.Exist("tag", 2))
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/cuigh/auxo/data"
+	"github.com/cuigh/auxo/errors"
 	"github.com/cuigh/auxo/ext/times"
 	"github.com/cuigh/auxo/log"
 	"github.com/cuigh/auxo/util/cast"
@@ -59,7 +60,14 @@ type Cacher interface {
 	Set(value interface{}, key string, args ...interface{})
 	Exist(key string, args ...interface{}) bool
 	Remove(key string, args ...interface{})
+	// GetMulti returns cached values of multiple items, each arg identifies an item, the result is assured of not nil.
+	GetMulti(key string, args ...interface{}) []data.Value
+	// SetMulti caches multiple items, values[i] is cached with args[i].
+	SetMulti(values []interface{}, key string, args ...interface{})
+	RemoveMulti(key string, args ...interface{})
 	RemoveGroup(key string)
+	// RemoveTag removes all items attached with any of the tags.
+	RemoveTag(tags ...string)
 }
 
 type KeyInfo struct {
@@ -69,6 +77,7 @@ type KeyInfo struct {
 
 type cacher struct {
 	p       Provider
+	bp      BatchProvider
 	enabled bool
 	eh      ErrorHandling
 	keyer   Keyer
@@ -87,6 +96,11 @@ func newCacher(p Provider, opts *Options) *cacher {
 		keys:    make(map[string]*KeyInfo),
 		logger:  log.Get(PkgName),
 	}
+	if bp, ok := p.(BatchProvider); ok {
+		c.bp = bp
+	} else {
+		c.bp = batchProvider{p}
+	}
 	for key, value := range opts.Keys {
 		args := strings.Split(value, ",")
 		info := &KeyInfo{
@@ -140,17 +154,19 @@ func (c *cacher) Set(value interface{}, key string, args ...interface{}) {
 		return
 	}
 
-	var err error
-	if info.Group == "" {
-		k := c.keyer(key, args...)
-		err = c.p.Set(k, value, info.Time)
-	} else {
+	args, tags := splitTags(args)
+	k := c.keyer(key, args...)
+	if info.Group != "" {
 		g := c.getGroup(info.Group, true)
 		if g == "" {
 			return
 		}
-		k := c.appendGroup(c.keyer(key, args...), g)
-		err = c.p.Set(k, value, info.Time)
+		k = c.appendGroup(k, g)
+	}
+
+	err := c.p.Set(k, value, info.Time)
+	if err == nil && len(tags) > 0 {
+		err = c.tag(k, info.Time, tags)
 	}
 	if err != nil {
 		c.handleError(err)
@@ -213,6 +229,71 @@ func (c *cacher) Remove(key string, args ...interface{}) {
 	}
 }
 
+func (c *cacher) GetMulti(key string, args ...interface{}) []data.Value {
+	values := make([]data.Value, len(args))
+	for i := range values {
+		values[i] = data.Nil
+	}
+	if !c.enabled || len(args) == 0 {
+		return values
+	}
+
+	keys, _ := c.getKeys(key, false, args)
+	if keys == nil {
+		return values
+	}
+
+	list, err := c.bp.GetMulti(keys...)
+	if err != nil {
+		c.handleError(err)
+		return values
+	}
+	for i, v := range list {
+		if v != nil {
+			values[i] = v
+		}
+	}
+	return values
+}
+
+func (c *cacher) SetMulti(values []interface{}, key string, args ...interface{}) {
+	if !c.enabled || len(args) == 0 {
+		return
+	}
+	if len(values) != len(args) {
+		c.handleError(errors.Format("values count(%d) doesn't match args count(%d)", len(values), len(args)))
+		return
+	}
+
+	keys, expiry := c.getKeys(key, true, args)
+	if keys == nil {
+		return
+	}
+
+	items := make(map[string]interface{}, len(keys))
+	for i, k := range keys {
+		items[k] = values[i]
+	}
+	if err := c.bp.SetMulti(items, expiry); err != nil {
+		c.handleError(err)
+	}
+}
+
+func (c *cacher) RemoveMulti(key string, args ...interface{}) {
+	if !c.enabled || len(args) == 0 {
+		return
+	}
+
+	keys, _ := c.getKeys(key, false, args)
+	if keys == nil {
+		return
+	}
+
+	if err := c.bp.RemoveMulti(keys...); err != nil {
+		c.handleError(err)
+	}
+}
+
 func (c *cacher) RemoveGroup(key string) {
 	if !c.enabled {
 		return
@@ -225,6 +306,41 @@ func (c *cacher) RemoveGroup(key string) {
 	}
 }
 
+func (c *cacher) RemoveTag(tags ...string) {
+	if !c.enabled || len(tags) == 0 {
+		return
+	}
+
+	tp, ok := c.p.(TagProvider)
+	if !ok {
+		c.handleError(errors.New("provider doesn't support tags"))
+		return
+	}
+
+	for _, tag := range tags {
+		if err := tp.RemoveTag(c.tagKey(tag)); err != nil {
+			c.handleError(err)
+		}
+	}
+}
+
+func (c *cacher) tag(key string, expiry time.Duration, tags []string) error {
+	tp, ok := c.p.(TagProvider)
+	if !ok {
+		return errors.New("provider doesn't support tags")
+	}
+
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = c.tagKey(tag)
+	}
+	return tp.Tag(key, expiry, keys...)
+}
+
+func (c *cacher) tagKey(tag string) string {
+	return c.keyer("#" + tag)
+}
+
 func (c *cacher) getGroup(key string, set bool) (g string) {
 	k := c.keyer(key)
 	value, err := c.p.Get(k)
@@ -248,6 +364,30 @@ func (c *cacher) getGroup(key string, set bool) (g string) {
 	return
 }
 
+// getKeys builds a full cache key for every arg, it returns nil if the group version is unavailable.
+func (c *cacher) getKeys(key string, set bool, args []interface{}) (keys []string, expiry time.Duration) {
+	info := c.getInfo(key)
+	if info == nil {
+		return
+	}
+
+	var g string
+	if info.Group != "" {
+		if g = c.getGroup(info.Group, set); g == "" {
+			return
+		}
+	}
+
+	keys = make([]string, len(args))
+	for i, arg := range args {
+		keys[i] = c.keyer(key, arg)
+		if g != "" {
+			keys[i] = c.appendGroup(keys[i], g)
+		}
+	}
+	return keys, info.Time
+}
+
 func (c *cacher) appendGroup(k, g string) string {
 	return k + "@" + g
 }
@@ -269,6 +409,57 @@ func (c *cacher) handleError(err error) {
 	}
 }
 
+// splitTags separates tags from key args.
+func splitTags(args []interface{}) ([]interface{}, []string) {
+	var tags []string
+	for i := 0; i < len(args); i++ {
+		if t, ok := args[i].(TagList); ok {
+			if tags == nil {
+				args = append([]interface{}(nil), args...)
+			}
+			tags = append(tags, t...)
+			args = append(args[:i], args[i+1:]...)
+			i--
+		}
+	}
+	return args, tags
+}
+
+// batchProvider implements BatchProvider by calling single-key methods of Provider one by one.
+type batchProvider struct {
+	Provider
+}
+
+func (p batchProvider) GetMulti(keys ...string) ([]data.Value, error) {
+	values := make([]data.Value, len(keys))
+	for i, key := range keys {
+		v, err := p.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (p batchProvider) SetMulti(items map[string]interface{}, expiry time.Duration) error {
+	for key, value := range items {
+		if err := p.Set(key, value, expiry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p batchProvider) RemoveMulti(keys ...string) error {
+	for _, key := range keys {
+		if err := p.Remove(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func prefix(prefix string) Keyer {
 	if prefix == "" {
 		prefix = "auxo:"
@@ -0,0 +1,730 @@
+package disk
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cuigh/auxo/byte/size"
+	"github.com/cuigh/auxo/cache"
+	"github.com/cuigh/auxo/data"
+	"github.com/cuigh/auxo/errors"
+	"github.com/cuigh/auxo/log"
+	"github.com/cuigh/auxo/util/cast"
+)
+
+const (
+	magic     = "AXC1"
+	tmpSuffix = ".tmp"
+	tagDir    = "tags"
+	// headerSize is the size of fixed header: magic(4) + expiry(8) + key length(2).
+	headerSize = 14
+)
+
+type entry struct {
+	path   string
+	size   int64
+	expiry int64 // unix nano, 0 means never expire
+	access int64 // unix nano
+}
+
+func (e *entry) Valid(now int64) bool {
+	return e.expiry == 0 || e.expiry > now
+}
+
+// Provider is disk provider implementation, every item is stored in a file under dir, sharded by key hash.
+// Tags are stored in files under `dir/tags`. Only files created by the provider are scanned and cleaned,
+// other files in dir are left untouched.
+//
+// Options:
+//   - dir: cache directory, default is $TMPDIR/auxo-cache
+//   - max_size: maximum total size of items, least recently used items are evicted down to 90% of it when exceeded, e.g. 1GB
+type Provider struct {
+	dir     string
+	maxSize int64
+	locker  sync.RWMutex
+	entries map[string]*entry
+	tags    map[string]map[string]struct{} // tag -> keys
+	size    int64
+	logger  log.Logger
+}
+
+func NewProvider(opts data.Map) (*Provider, error) {
+	dir := cast.ToString(opts.Get("dir"))
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "auxo-cache")
+	}
+
+	var maxSize size.Size
+	switch v := opts.Get("max_size").(type) {
+	case nil:
+	case string:
+		s, err := size.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		maxSize = s
+	default:
+		maxSize = size.Size(cast.ToInt64(v))
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		dir:     dir,
+		maxSize: int64(maxSize),
+		entries: make(map[string]*entry),
+		tags:    make(map[string]map[string]struct{}),
+		logger:  log.Get(cache.PkgName),
+	}
+	if err := p.scan(); err != nil {
+		return nil, err
+	}
+	if err := p.loadTags(); err != nil {
+		return nil, err
+	}
+	go p.removeExpired()
+	return p, nil
+}
+
+func (p *Provider) Get(key string) (data.Value, error) {
+	now := time.Now().UnixNano()
+
+	p.locker.RLock()
+	e, ok := p.entries[key]
+	p.locker.RUnlock()
+	if !ok || !e.Valid(now) {
+		return data.Nil, nil
+	}
+
+	b, err := os.ReadFile(e.path)
+	if os.IsNotExist(err) {
+		p.drop(key, e)
+		return data.Nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	k, _, v, err := parse(b)
+	if err != nil {
+		return nil, err
+	} else if k != key {
+		return data.Nil, nil
+	}
+	atomic.StoreInt64(&e.access, now)
+	return value(v), nil
+}
+
+func (p *Provider) Set(key string, v interface{}, expiry time.Duration) error {
+	b, err := encode(v)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e := &entry{
+		path:   p.path(key),
+		access: now.UnixNano(),
+	}
+	if expiry > 0 {
+		e.expiry = now.Add(expiry).UnixNano()
+	}
+	if e.size, err = p.write(e.path, key, e.expiry, b); err != nil {
+		return err
+	}
+
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	if old, ok := p.entries[key]; ok {
+		p.size -= old.size
+	}
+	p.entries[key] = e
+	p.size += e.size
+	p.evict()
+	return nil
+}
+
+func (p *Provider) Remove(key string) error {
+	p.locker.Lock()
+	e, ok := p.entries[key]
+	if ok {
+		delete(p.entries, key)
+		p.size -= e.size
+	}
+	p.locker.Unlock()
+
+	if ok {
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Provider) Exist(key string) (bool, error) {
+	p.locker.RLock()
+	e, ok := p.entries[key]
+	p.locker.RUnlock()
+	return ok && e.Valid(time.Now().UnixNano()), nil
+}
+
+func (p *Provider) GetMulti(keys ...string) ([]data.Value, error) {
+	values := make([]data.Value, len(keys))
+	for i, key := range keys {
+		v, err := p.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (p *Provider) SetMulti(items map[string]interface{}, expiry time.Duration) error {
+	for key, value := range items {
+		if err := p.Set(key, value, expiry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Provider) RemoveMulti(keys ...string) error {
+	for _, key := range keys {
+		if err := p.Remove(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tag appends key to files of tags, so tags survive restarts.
+func (p *Provider) Tag(key string, _ time.Duration, tags ...string) error {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	for _, tag := range tags {
+		keys := p.tags[tag]
+		if _, ok := keys[key]; ok {
+			continue
+		}
+
+		if err := p.appendTag(tag, keys == nil, key); err != nil {
+			return err
+		}
+		if keys == nil {
+			keys = make(map[string]struct{})
+			p.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	return nil
+}
+
+func (p *Provider) RemoveTag(tag string) error {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	for key := range p.tags[tag] {
+		if e, ok := p.entries[key]; ok {
+			p.remove(key, e)
+		}
+	}
+	delete(p.tags, tag)
+	if err := os.Remove(p.tagPath(tag)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (p *Provider) path(key string) string {
+	name := hash(key)
+	return filepath.Join(p.dir, name[:2], name)
+}
+
+func (p *Provider) tagPath(tag string) string {
+	return filepath.Join(p.dir, tagDir, hash(tag))
+}
+
+// appendTag appends key to tag file, the first line of file is the tag itself. Lines are quoted,
+// so keys can contain any character. The caller must hold the write lock.
+func (p *Provider) appendTag(tag string, create bool, key string) error {
+	path := p.tagPath(tag)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	var b []byte
+	if create {
+		if info, e := f.Stat(); e == nil && info.Size() == 0 {
+			b = append(strconv.AppendQuote(b, tag), '\n')
+		}
+	}
+	b = append(strconv.AppendQuote(b, key), '\n')
+	_, err = f.Write(b)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// loadTags rebuilds tag index from tag files, keys which no longer exist are dropped.
+func (p *Provider) loadTags() error {
+	dir := filepath.Join(p.dir, tagDir)
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !isHash(file.Name()) {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		tag, keys, err := readTag(path)
+		if err != nil || hash(tag) != file.Name() {
+			p.logger.Warnf("cache > skip invalid tag file '%s'", path)
+			continue
+		}
+
+		m := make(map[string]struct{})
+		for _, key := range keys {
+			if _, ok := p.entries[key]; ok {
+				m[key] = struct{}{}
+			}
+		}
+		if len(m) > 0 {
+			p.tags[tag] = m
+		}
+		if len(m) == len(keys) {
+			continue
+		}
+
+		// compact tag file
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+		for key := range m {
+			if err = p.appendTag(tag, true, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// write writes item to a temporary file, then renames it to path to make the writing atomic.
+func (p *Provider) write(path, key string, expiry int64, v []byte) (int64, error) {
+	if len(key) > 0xFFFF {
+		return 0, errors.New("key is too long")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*"+tmpSuffix)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+
+	header := make([]byte, headerSize, headerSize+len(key))
+	copy(header, magic)
+	binary.BigEndian.PutUint64(header[4:], uint64(expiry))
+	binary.BigEndian.PutUint16(header[12:], uint16(len(key)))
+	header = append(header, key...)
+
+	if _, err = f.Write(header); err == nil {
+		_, err = f.Write(v)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(header) + len(v)), nil
+}
+
+// scan rebuilds index from files in cache directory. Only shard directories and files named by key hash
+// are scanned, other files are skipped, so it is safe to point dir to an existing directory.
+func (p *Provider) scan() error {
+	now := time.Now().UnixNano()
+	shards, err := os.ReadDir(p.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		if !shard.IsDir() || !isShard(shard.Name()) {
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(p.dir, shard.Name()))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err = p.load(shard.Name(), file, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Provider) load(shard string, file fs.DirEntry, now int64) error {
+	name := file.Name()
+	path := filepath.Join(p.dir, shard, name)
+	if file.IsDir() || len(name) < 40 || !isHash(name[:40]) || name[:2] != shard {
+		p.logger.Debugf("cache > skip unknown file '%s'", path)
+		return nil
+	}
+
+	// temporary files left by crashed writers
+	if strings.HasSuffix(name, tmpSuffix) && strings.HasPrefix(name[40:], "-") {
+		return removeFile(path)
+	}
+	if len(name) != 40 {
+		p.logger.Debugf("cache > skip unknown file '%s'", path)
+		return nil
+	}
+
+	key, expiry, err := readHeader(path)
+	if err != nil {
+		p.logger.Warnf("cache > remove invalid cache file '%s': %s", path, err)
+		return removeFile(path)
+	}
+	if hash(key) != name {
+		p.logger.Warnf("cache > remove invalid cache file '%s': key mismatch", path)
+		return removeFile(path)
+	}
+	if expiry != 0 && expiry <= now {
+		return removeFile(path)
+	}
+
+	info, err := file.Info()
+	if err != nil {
+		return err
+	}
+	p.entries[key] = &entry{
+		path:   path,
+		size:   info.Size(),
+		expiry: expiry,
+		access: info.ModTime().UnixNano(),
+	}
+	p.size += info.Size()
+	return nil
+}
+
+// evict removes expired items first, then least recently used items until total size is under the low-water
+// mark (90% of max_size), so the index is not sorted again on every write once the limit is reached.
+// The caller must hold the write lock.
+func (p *Provider) evict() {
+	if p.maxSize <= 0 || p.size <= p.maxSize {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	entries := make([]*entry, 0, len(p.entries))
+	keys := make(map[*entry]string, len(p.entries))
+	for k, e := range p.entries {
+		entries = append(entries, e)
+		keys[e] = k
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		vi, vj := entries[i].Valid(now), entries[j].Valid(now)
+		if vi != vj {
+			return !vi
+		}
+		return atomic.LoadInt64(&entries[i].access) < atomic.LoadInt64(&entries[j].access)
+	})
+	low := p.maxSize - p.maxSize/10
+	for _, e := range entries {
+		if p.size <= low {
+			break
+		}
+		p.remove(keys[e], e)
+	}
+}
+
+// remove deletes item from index and disk, the caller must hold the write lock.
+func (p *Provider) remove(key string, e *entry) {
+	delete(p.entries, key)
+	p.size -= e.size
+	if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+		p.logger.Warnf("cache > remove cache file '%s' failed: %s", e.path, err)
+	}
+}
+
+func (p *Provider) drop(key string, e *entry) {
+	p.locker.Lock()
+	if p.entries[key] == e {
+		delete(p.entries, key)
+		p.size -= e.size
+	}
+	p.locker.Unlock()
+}
+
+func (p *Provider) removeExpired() {
+	for {
+		time.Sleep(time.Minute * 10)
+
+		now := time.Now().UnixNano()
+		p.locker.Lock()
+		for key, e := range p.entries {
+			if !e.Valid(now) {
+				p.remove(key, e)
+			}
+		}
+		p.locker.Unlock()
+	}
+}
+
+func readTag(path string) (tag string, keys []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 0x20000)
+	for scanner.Scan() {
+		var s string
+		if s, err = strconv.Unquote(scanner.Text()); err != nil {
+			return
+		}
+		if tag == "" {
+			tag = s
+		} else {
+			keys = append(keys, s)
+		}
+	}
+	err = scanner.Err()
+	return
+}
+
+func readHeader(path string) (key string, expiry int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	header := make([]byte, headerSize)
+	if _, err = io.ReadFull(f, header); err != nil {
+		return
+	}
+	if string(header[:4]) != magic {
+		return "", 0, errors.New("invalid file format")
+	}
+
+	b := make([]byte, binary.BigEndian.Uint16(header[12:]))
+	if _, err = io.ReadFull(f, b); err != nil {
+		return
+	}
+	return string(b), int64(binary.BigEndian.Uint64(header[4:])), nil
+}
+
+func parse(b []byte) (key string, expiry int64, v []byte, err error) {
+	if len(b) < headerSize || string(b[:4]) != magic {
+		return "", 0, nil, errors.New("invalid file format")
+	}
+
+	n := headerSize + int(binary.BigEndian.Uint16(b[12:]))
+	if len(b) < n {
+		return "", 0, nil, errors.New("invalid file format")
+	}
+	return string(b[headerSize:n]), int64(binary.BigEndian.Uint64(b[4:])), b[n:], nil
+}
+
+func hash(s string) string {
+	h := sha1.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func isHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
+
+func isShard(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
+
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func encode(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return t, nil
+	case string:
+		return []byte(t), nil
+	case bool, float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return []byte(cast.ToString(t)), nil
+	case encoding.BinaryMarshaler:
+		return t.MarshalBinary()
+	default:
+		buf := new(bytes.Buffer)
+		err := gob.NewEncoder(buf).Encode(v)
+		return buf.Bytes(), err
+	}
+}
+
+type value []byte
+
+func (v value) IsNil() bool {
+	return len(v) == 0
+}
+
+func (v value) Scan(i interface{}) (err error) {
+	switch t := i.(type) {
+	case *[]byte:
+		*t = append([]byte(nil), v...)
+	case *string:
+		*t = string(v)
+	case *bool:
+		*t, err = v.Bool()
+	case *int:
+		*t, err = v.Int()
+	case *int8:
+		*t, err = v.Int8()
+	case *int16:
+		*t, err = v.Int16()
+	case *int32:
+		*t, err = v.Int32()
+	case *int64:
+		*t, err = v.Int64()
+	case *uint:
+		*t, err = v.Uint()
+	case *uint8:
+		*t, err = v.Uint8()
+	case *uint16:
+		*t, err = v.Uint16()
+	case *uint32:
+		*t, err = v.Uint32()
+	case *uint64:
+		*t, err = v.Uint64()
+	case *float32:
+		*t, err = v.Float32()
+	case *float64:
+		*t, err = v.Float64()
+	case encoding.BinaryUnmarshaler:
+		err = t.UnmarshalBinary(v)
+	default:
+		err = gob.NewDecoder(bytes.NewReader(v)).Decode(i)
+	}
+	return
+}
+
+func (v value) Bytes() ([]byte, error) {
+	return v, nil
+}
+
+func (v value) Bool() (bool, error) {
+	return cast.TryToBool(string(v))
+}
+
+func (v value) Int() (int, error) {
+	return cast.TryToInt(string(v))
+}
+
+func (v value) Int8() (int8, error) {
+	return cast.TryToInt8(string(v))
+}
+
+func (v value) Int16() (int16, error) {
+	return cast.TryToInt16(string(v))
+}
+
+func (v value) Int32() (int32, error) {
+	return cast.TryToInt32(string(v))
+}
+
+func (v value) Int64() (int64, error) {
+	return cast.TryToInt64(string(v))
+}
+
+func (v value) Uint() (uint, error) {
+	return cast.TryToUint(string(v))
+}
+
+func (v value) Uint8() (uint8, error) {
+	return cast.TryToUint8(string(v))
+}
+
+func (v value) Uint16() (uint16, error) {
+	return cast.TryToUint16(string(v))
+}
+
+func (v value) Uint32() (uint32, error) {
+	return cast.TryToUint32(string(v))
+}
+
+func (v value) Uint64() (uint64, error) {
+	return cast.TryToUint64(string(v))
+}
+
+func (v value) Float32() (float32, error) {
+	return cast.TryToFloat32(string(v))
+}
+
+func (v value) Float64() (float64, error) {
+	return cast.TryToFloat64(string(v))
+}
+
+func (v value) String() (string, error) {
+	return string(v), nil
+}
+
+func init() {
+	cache.Register("disk", func(opts data.Map) (cache.Provider, error) {
+		return NewProvider(opts)
+	})
+}
@@ -0,0 +1,226 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cuigh/auxo/data"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+const key = "key"
+
+type User struct {
+	Name string
+}
+
+func TestProvider(t *testing.T) {
+	var (
+		value  = 10
+		actual int
+	)
+
+	p, err := NewProvider(data.Map{"dir": t.TempDir()})
+	assert.NoError(t, err)
+
+	err = p.Set(key, value, time.Minute)
+	assert.NoError(t, err)
+
+	ok, err := p.Exist(key)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	v, err := p.Get(key)
+	assert.NoError(t, err)
+
+	actual, err = v.Int()
+	assert.NoError(t, err)
+	assert.Equal(t, value, actual)
+
+	err = p.Remove(key)
+	assert.NoError(t, err)
+
+	ok, err = p.Exist(key)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestProvider_Scan(t *testing.T) {
+	dir := t.TempDir()
+
+	p, err := NewProvider(data.Map{"dir": dir})
+	assert.NoError(t, err)
+	assert.NoError(t, p.Set("k1", "v1", time.Minute))
+	assert.NoError(t, p.Set("k2", "v2", time.Millisecond))
+
+	time.Sleep(2 * time.Millisecond)
+
+	// reopen to simulate restart
+	p, err = NewProvider(data.Map{"dir": dir})
+	assert.NoError(t, err)
+
+	v, err := p.Get("k1")
+	assert.NoError(t, err)
+	s, err := v.String()
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", s)
+
+	ok, err := p.Exist("k2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestProvider_ForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		filepath.Join(dir, "notes.txt"),
+		filepath.Join(dir, "backup.tmp"),
+		filepath.Join(dir, "ab", "photo.jpg"),
+		filepath.Join(dir, "docs", "0123456789abcdef0123456789abcdef01234567"),
+	}
+	for _, f := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(f), 0755))
+		assert.NoError(t, os.WriteFile(f, []byte("user data"), 0644))
+	}
+
+	p, err := NewProvider(data.Map{"dir": dir})
+	assert.NoError(t, err)
+	assert.NoError(t, p.Set("k1", "v1", time.Minute))
+
+	_, err = NewProvider(data.Map{"dir": dir})
+	assert.NoError(t, err)
+	for _, f := range files {
+		_, err = os.Stat(f)
+		assert.NoError(t, err)
+	}
+}
+
+func TestProvider_Multi(t *testing.T) {
+	p, err := NewProvider(data.Map{"dir": t.TempDir()})
+	assert.NoError(t, err)
+
+	err = p.SetMulti(map[string]interface{}{"k1": 1, "k2": 2}, time.Minute)
+	assert.NoError(t, err)
+
+	values, err := p.GetMulti("k1", "k2", "k3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(values))
+	i, err := values[1].Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, i)
+	assert.True(t, values[2].IsNil())
+
+	err = p.RemoveMulti("k1", "k2")
+	assert.NoError(t, err)
+	ok, err := p.Exist("k1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestProvider_Tag(t *testing.T) {
+	dir := t.TempDir()
+	p, err := NewProvider(data.Map{"dir": dir})
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.Set("k1", 1, time.Minute))
+	assert.NoError(t, p.Set("k2", 2, time.Minute))
+	assert.NoError(t, p.Set("k3", 3, time.Minute))
+	assert.NoError(t, p.Tag("k1", time.Minute, "t1", "t2"))
+	assert.NoError(t, p.Tag("k2", time.Minute, "t2"))
+
+	// tags survive restart
+	p, err = NewProvider(data.Map{"dir": dir})
+	assert.NoError(t, err)
+	assert.NoError(t, p.RemoveTag("t2"))
+
+	for k, expected := range map[string]bool{"k1": false, "k2": false, "k3": true} {
+		ok, err := p.Exist(k)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ok)
+	}
+}
+
+func TestProvider_Evict(t *testing.T) {
+	p, err := NewProvider(data.Map{"dir": t.TempDir(), "max_size": 100})
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.Set("k1", make([]byte, 40), time.Minute))
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, p.Set("k2", make([]byte, 40), time.Minute))
+
+	ok, err := p.Exist("k1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = p.Exist("k2")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestProvider_EvictLowWater(t *testing.T) {
+	p, err := NewProvider(data.Map{"dir": t.TempDir(), "max_size": 100})
+	assert.NoError(t, err)
+
+	// every item takes 20 bytes: header(14) + key(2) + value(4)
+	keys := []string{"k1", "k2", "k3", "k4", "k5", "k6"}
+	for _, k := range keys {
+		assert.NoError(t, p.Set(k, make([]byte, 4), time.Minute))
+		time.Sleep(time.Millisecond)
+	}
+
+	// 120 bytes exceed the limit, items are evicted down to 90 bytes
+	for i, k := range keys {
+		ok, err := p.Exist(k)
+		assert.NoError(t, err)
+		assert.Equal(t, i >= 2, ok)
+	}
+}
+
+func TestValue(t *testing.T) {
+	testCases := []struct {
+		Actual   interface{}
+		IsNil    bool
+		Expected interface{}
+		New      func() interface{}
+	}{
+		{nil, true, nil, nil},
+		{true, false, true, func() interface{} { return new(bool) }},
+		{"test", false, "test", func() interface{} { return new(string) }},
+		{[]byte("test"), false, []byte("test"), func() interface{} { return &[]byte{} }},
+		{float32(1.5), false, float32(1.5), func() interface{} { return new(float32) }},
+		{float64(1.5), false, float64(1.5), func() interface{} { return new(float64) }},
+		{int(1), false, int(1), func() interface{} { return new(int) }},
+		{int8(1), false, int8(1), func() interface{} { return new(int8) }},
+		{int16(1), false, int16(1), func() interface{} { return new(int16) }},
+		{int32(1), false, int32(1), func() interface{} { return new(int32) }},
+		{int64(1), false, int64(1), func() interface{} { return new(int64) }},
+		{User{"test"}, false, User{"test"}, func() interface{} { return new(User) }},
+		{&User{"test"}, false, User{"test"}, func() interface{} { return new(User) }},
+	}
+
+	p, err := NewProvider(data.Map{"dir": t.TempDir()})
+	assert.NoError(t, err)
+
+	for _, tc := range testCases {
+		err := p.Set(key, tc.Actual, time.Minute)
+		assert.NoError(t, err)
+
+		ok, err := p.Exist(key)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		v, err := p.Get(key)
+		assert.NoError(t, err)
+		if tc.IsNil {
+			assert.True(t, v.IsNil())
+		} else {
+			i := tc.New()
+			err = v.Scan(i)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Expected, reflect.ValueOf(i).Elem().Interface())
+		}
+	}
+}
@@ -151,11 +151,13 @@ func (i *item) Valid() bool {
 type Provider struct {
 	locker sync.RWMutex
 	items  map[string]*item
+	tags   map[string]map[string]struct{}
 }
 
 func NewProvider() *Provider {
 	p := &Provider{
 		items: make(map[string]*item),
+		tags:  make(map[string]map[string]struct{}),
 	}
 	go p.removeExpired()
 	return p
@@ -196,6 +198,66 @@ func (p *Provider) Exist(key string) (bool, error) {
 	return ok && item.expiry.After(time.Now()), nil
 }
 
+func (p *Provider) GetMulti(keys ...string) ([]data.Value, error) {
+	values := make([]data.Value, len(keys))
+	p.locker.RLock()
+	for i, key := range keys {
+		if item, ok := p.items[key]; ok && item.Valid() {
+			values[i] = item
+		} else {
+			values[i] = data.Nil
+		}
+	}
+	p.locker.RUnlock()
+	return values, nil
+}
+
+func (p *Provider) SetMulti(items map[string]interface{}, expiry time.Duration) error {
+	t := time.Now().Add(expiry)
+	p.locker.Lock()
+	for key, value := range items {
+		p.items[key] = &item{
+			value:  value,
+			expiry: t,
+		}
+	}
+	p.locker.Unlock()
+	return nil
+}
+
+func (p *Provider) RemoveMulti(keys ...string) error {
+	p.locker.Lock()
+	for _, key := range keys {
+		delete(p.items, key)
+	}
+	p.locker.Unlock()
+	return nil
+}
+
+func (p *Provider) Tag(key string, _ time.Duration, tags ...string) error {
+	p.locker.Lock()
+	for _, tag := range tags {
+		keys := p.tags[tag]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			p.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	p.locker.Unlock()
+	return nil
+}
+
+func (p *Provider) RemoveTag(tag string) error {
+	p.locker.Lock()
+	for key := range p.tags[tag] {
+		delete(p.items, key)
+	}
+	delete(p.tags, tag)
+	p.locker.Unlock()
+	return nil
+}
+
 func (p *Provider) removeExpired() {
 	for {
 		time.Sleep(time.Minute * 10)
@@ -210,6 +272,16 @@ func (p *Provider) removeExpired() {
 		for _, key := range keys {
 			delete(p.items, key)
 		}
+		for tag, keys := range p.tags {
+			for key := range keys {
+				if _, ok := p.items[key]; !ok {
+					delete(keys, key)
+				}
+			}
+			if len(keys) == 0 {
+				delete(p.tags, tag)
+			}
+		}
 		p.locker.Unlock()
 	}
 }
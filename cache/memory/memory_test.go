@@ -40,6 +40,45 @@ func TestProvider(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestProvider_Multi(t *testing.T) {
+	p := NewProvider()
+
+	err := p.SetMulti(map[string]interface{}{"k1": 1, "k2": 2}, time.Minute)
+	assert.NoError(t, err)
+
+	values, err := p.GetMulti("k1", "k2", "k3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(values))
+	assert.True(t, values[2].IsNil())
+
+	actual, err := values[1].Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, actual)
+
+	err = p.RemoveMulti("k1", "k2")
+	assert.NoError(t, err)
+
+	ok, err := p.Exist("k1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestProvider_Tag(t *testing.T) {
+	p := NewProvider()
+
+	err := p.Set("k1", 1, time.Minute)
+	assert.NoError(t, err)
+	err = p.Tag("k1", time.Minute, "t1", "t2")
+	assert.NoError(t, err)
+
+	err = p.RemoveTag("t2")
+	assert.NoError(t, err)
+
+	ok, err := p.Exist("k1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
 func TestValue(t *testing.T) {
 	testCases := []struct {
 		Actual   interface{}
@@ -1,19 +1,52 @@
 package redis
 
 import (
-	"bytes"
 	"encoding"
-	"encoding/gob"
 	"time"
 
+	"github.com/cuigh/auxo/byte/size"
 	"github.com/cuigh/auxo/cache"
 	"github.com/cuigh/auxo/data"
 	"github.com/cuigh/auxo/db/redis"
 	"github.com/cuigh/auxo/util/cast"
 )
 
+// tagScript adds a key to the tag set, and extends expiry of the set to cover the item.
+const tagScript = `
+redis.call('SADD', KEYS[1], ARGV[1])
+local expiry = tonumber(ARGV[2])
+if expiry <= 0 then
+	redis.call('PERSIST', KEYS[1])
+else
+	local ttl = redis.call('TTL', KEYS[1])
+	if ttl >= 0 and ttl < expiry then
+		redis.call('EXPIRE', KEYS[1], expiry)
+	elseif ttl == -1 and redis.call('SCARD', KEYS[1]) == 1 then
+		redis.call('EXPIRE', KEYS[1], expiry)
+	end
+end
+return 1`
+
+// untagScript removes the tag set and all keys in it atomically, keys are deleted in batches to
+// keep arguments of `unpack` within the stack limit of Lua.
+const untagScript = `
+local keys = redis.call('SMEMBERS', KEYS[1])
+for i = 1, #keys, 1000 do
+	redis.call('DEL', unpack(keys, i, math.min(i + 999, #keys)))
+end
+redis.call('DEL', KEYS[1])
+return #keys`
+
+// Provider is redis provider implementation.
+//
+// Options:
+//   - db: redis config name, default is "cache"
+//   - serializer: gob/json/msgpack/proto, values are written in legacy gob format without header if not set
+//   - compressor: gzip/snappy, only works with serializer
+//   - compress_threshold: minimum size of a value to compress, e.g. 1KB
 type Provider struct {
 	client redis.Client
+	format *format
 }
 
 func NewProvider(opts data.Map) (*Provider, error) {
@@ -21,11 +54,29 @@ func NewProvider(opts data.Map) (*Provider, error) {
 	if db == "" {
 		db = "cache"
 	}
+
+	var threshold size.Size
+	switch v := opts.Get("compress_threshold").(type) {
+	case nil:
+	case string:
+		s, err := size.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		threshold = s
+	default:
+		threshold = size.Size(cast.ToInt(v))
+	}
+	f, err := newFormat(cast.ToString(opts.Get("serializer")), cast.ToString(opts.Get("compressor")), int(threshold))
+	if err != nil {
+		return nil, err
+	}
+
 	cmd, err := redis.Open(db)
 	if err != nil {
 		return nil, err
 	}
-	return &Provider{client: cmd}, nil
+	return &Provider{client: cmd, format: f}, nil
 }
 
 func (p *Provider) Exist(key string) (bool, error) {
@@ -56,6 +107,55 @@ func (p *Provider) Set(key string, value interface{}, expiry time.Duration) erro
 	return p.client.Set(key, v, expiry).Err()
 }
 
+func (p *Provider) GetMulti(keys ...string) ([]data.Value, error) {
+	list, err := p.client.MGet(keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]data.Value, len(list))
+	for i, item := range list {
+		if s, ok := item.(string); ok {
+			values[i] = (*value)(redis.NewStringResult(s, nil))
+		} else {
+			values[i] = data.Nil
+		}
+	}
+	return values, nil
+}
+
+func (p *Provider) SetMulti(items map[string]interface{}, expiry time.Duration) error {
+	_, err := p.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for key, item := range items {
+			v, err := p.encode(item)
+			if err != nil {
+				return err
+			}
+			pipe.Set(key, v, expiry)
+		}
+		return nil
+	})
+	return err
+}
+
+func (p *Provider) RemoveMulti(keys ...string) error {
+	return p.client.Del(keys...).Err()
+}
+
+func (p *Provider) Tag(key string, expiry time.Duration, tags ...string) error {
+	_, err := p.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			pipe.Eval(tagScript, []string{tag}, key, int64((expiry+time.Second-1)/time.Second))
+		}
+		return nil
+	})
+	return err
+}
+
+func (p *Provider) RemoveTag(tag string) error {
+	return p.client.Eval(untagScript, []string{tag}).Err()
+}
+
 func (p *Provider) encode(value interface{}) (r interface{}, err error) {
 	switch v := value.(type) {
 	case nil, bool, string, []byte, float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
@@ -63,12 +163,7 @@ func (p *Provider) encode(value interface{}) (r interface{}, err error) {
 	case encoding.BinaryMarshaler:
 		r = v
 	default:
-		buf := new(bytes.Buffer)
-		encoder := gob.NewEncoder(buf)
-		err = encoder.Encode(value)
-		if err == nil {
-			r = buf.Bytes()
-		}
+		r, err = p.format.encode(value)
 	}
 	return
 }
@@ -95,8 +190,7 @@ func (v *value) Scan(i interface{}) error {
 		if err != nil {
 			return err
 		}
-		encoder := gob.NewDecoder(bytes.NewBuffer(b))
-		return encoder.Decode(i)
+		return decode(b, i)
 	}
 }
 
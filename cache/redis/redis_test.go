@@ -47,6 +47,26 @@ func TestProvider(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestProvider_Multi(t *testing.T) {
+	p, err := NewProvider(data.Map{"db": "cache"})
+	assert.NoError(t, err)
+
+	err = p.SetMulti(map[string]interface{}{"k1": 1, "k2": 2}, time.Minute)
+	assert.NoError(t, err)
+
+	values, err := p.GetMulti("k1", "k2", "k3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(values))
+	assert.True(t, values[2].IsNil())
+
+	actual, err := values[1].Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, actual)
+
+	err = p.RemoveMulti("k1", "k2")
+	assert.NoError(t, err)
+}
+
 func TestValue(t *testing.T) {
 	testCases := []struct {
 		Actual   interface{}
@@ -93,6 +113,36 @@ func TestValue(t *testing.T) {
 	}
 }
 
+func TestFormat(t *testing.T) {
+	legacy, err := newFormat("", "", 0)
+	assert.NoError(t, err)
+
+	for _, serializer := range []string{"", "gob", "json", "msgpack"} {
+		for _, compressor := range []string{"", "gzip", "snappy"} {
+			f, err := newFormat(serializer, compressor, 0)
+			assert.NoError(t, err)
+
+			b, err := f.encode(&User{"test"})
+			assert.NoError(t, err)
+
+			u := &User{}
+			err = decode(b, u)
+			assert.NoError(t, err)
+			assert.Equal(t, "test", u.Name)
+
+			// legacy values are still readable after serializer changed
+			b, err = legacy.encode(&User{"legacy"})
+			assert.NoError(t, err)
+			err = decode(b, u)
+			assert.NoError(t, err)
+			assert.Equal(t, "legacy", u.Name)
+		}
+	}
+
+	_, err = newFormat("xml", "", 0)
+	assert.Error(t, err)
+}
+
 func BenchmarkProvider_Get(b *testing.B) {
 	b.ReportAllocs()
 
@@ -0,0 +1,191 @@
+package redis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+
+	"github.com/cuigh/auxo/errors"
+	"github.com/golang/snappy"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// marker is the first byte of a formatted value, the second byte records serializer and compressor.
+// gob never starts a stream with zero, so formatted values can be distinguished from legacy gob values.
+const marker byte = 0
+
+// Serializer encodes values to bytes and decodes them back.
+type Serializer interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+// Compressor compresses and decompresses serialized values.
+type Compressor interface {
+	Compress(b []byte) ([]byte, error)
+	Decompress(b []byte) ([]byte, error)
+}
+
+var (
+	serializerIDs = map[string]byte{"gob": 1, "json": 2, "msgpack": 3, "proto": 4}
+	compressorIDs = map[string]byte{"gzip": 1, "snappy": 2}
+	serializers   = map[byte]Serializer{
+		1: gobSerializer{},
+		2: jsonSerializer{},
+		3: msgpackSerializer{},
+		4: protoSerializer{},
+	}
+	compressors = map[byte]Compressor{
+		1: gzipCompressor{},
+		2: snappyCompressor{},
+	}
+)
+
+type format struct {
+	serializer byte
+	compressor byte
+	threshold  int
+}
+
+func newFormat(serializer, compressor string, threshold int) (*format, error) {
+	f := &format{threshold: threshold}
+	if serializer != "" {
+		if f.serializer = serializerIDs[serializer]; f.serializer == 0 {
+			return nil, errors.Format("unknown cache serializer: %s", serializer)
+		}
+	}
+	if compressor != "" {
+		if f.compressor = compressorIDs[compressor]; f.compressor == 0 {
+			return nil, errors.Format("unknown cache compressor: %s", compressor)
+		}
+	}
+	return f, nil
+}
+
+func (f *format) encode(v interface{}) ([]byte, error) {
+	if f.serializer == 0 {
+		return gobSerializer{}.Marshal(v)
+	}
+
+	b, err := serializers[f.serializer].Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var c byte
+	if f.compressor != 0 && len(b) >= f.threshold {
+		if b, err = compressors[f.compressor].Compress(b); err != nil {
+			return nil, err
+		}
+		c = f.compressor
+	}
+	return append([]byte{marker, c<<4 | f.serializer}, b...), nil
+}
+
+// decode decodes b by the format recorded in its header, so values written with other formats can still be read.
+func decode(b []byte, v interface{}) (err error) {
+	if len(b) < 2 || b[0] != marker {
+		return gobSerializer{}.Unmarshal(b, v)
+	}
+
+	s, c := serializers[b[1]&0x0F], b[1]>>4
+	if s == nil {
+		return errors.Format("unknown cache serializer id: %d", b[1]&0x0F)
+	}
+
+	b = b[2:]
+	if c != 0 {
+		compressor := compressors[c]
+		if compressor == nil {
+			return errors.Format("unknown cache compressor id: %d", c)
+		}
+		if b, err = compressor.Decompress(b); err != nil {
+			return err
+		}
+	}
+	return s.Unmarshal(b, v)
+}
+
+type gobSerializer struct{}
+
+func (gobSerializer) Marshal(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := gob.NewEncoder(buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobSerializer) Unmarshal(b []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewBuffer(b)).Decode(v)
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSerializer) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+type msgpackSerializer struct{}
+
+func (msgpackSerializer) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackSerializer) Unmarshal(b []byte, v interface{}) error {
+	return msgpack.Unmarshal(b, v)
+}
+
+type protoSerializer struct{}
+
+func (protoSerializer) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Marshal(m)
+	}
+	return nil, errors.Format("value of type %T is not a proto.Message", v)
+}
+
+func (protoSerializer) Unmarshal(b []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(b, m)
+	}
+	return errors.Format("value of type %T is not a proto.Message", v)
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Compress(b []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) Compress(b []byte) ([]byte, error) {
+	return snappy.Encode(nil, b), nil
+}
+
+func (snappyCompressor) Decompress(b []byte) ([]byte, error) {
+	return snappy.Decode(nil, b)
+}
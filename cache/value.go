@@ -1,44 +1,94 @@
 package cache
 
 import (
+	"context"
+	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/cuigh/auxo/errors"
 )
 
 // Value is a simple auto refresh cache hold.
 type Value[T any] struct {
-	locker sync.Mutex
+	locker sync.RWMutex
+	gen    uint64 // increased by Reset to discard in-flight loads
 	value  interface{}
 	next   time.Time
+	retry  time.Time
+	fails  int
+	call   *loadCall[T]
 	TTL    time.Duration
-	Load   func() (T, error)
+	// Jitter randomizes every TTL by ±Jitter*TTL to spread reloading, valid range is [0, 1).
+	Jitter float64
+	// Async enables refresh-ahead mode: an expired value is returned immediately while it is reloaded in background.
+	Async bool
+	// MaxStale limits how long an expired value can be served in Async mode, 0 means no limit.
+	MaxStale time.Duration
+	// Backoff is the delay before retrying a failed background refresh, it doubles on each failure up to TTL.
+	Backoff time.Duration
+	Load    func() (T, error)
+}
+
+type loadCall[T any] struct {
+	gen   uint64
+	done  chan struct{}
+	value T
+	err   error
 }
 
 // Get return cached value, it will return expired value if dirty is true and loading failed.
 func (v *Value[T]) Get(dirty ...bool) (value T, err error) {
-	if v.value != nil && time.Now().Before(v.next) {
-		return v.value.(T), nil
-	}
+	return v.GetContext(context.Background(), dirty...)
+}
 
-	v.locker.Lock()
-	defer v.locker.Unlock()
+// GetContext is like Get, but it stops waiting for loading when ctx is done.
+func (v *Value[T]) GetContext(ctx context.Context, dirty ...bool) (value T, err error) {
+	now := time.Now()
 
-	if v.value != nil && time.Now().Before(v.next) {
-		return v.value.(T), nil
+	v.locker.RLock()
+	if v.value != nil && now.Before(v.next) {
+		value = v.value.(T)
+		v.locker.RUnlock()
+		return
 	}
+	v.locker.RUnlock()
 
-	value, err = v.Load()
-	if err == nil {
-		ttl := v.TTL
-		if ttl == 0 {
-			ttl = time.Hour
+	v.locker.Lock()
+	if v.value != nil {
+		if now.Before(v.next) {
+			value = v.value.(T)
+			v.locker.Unlock()
+			return
 		}
-		v.value, v.next = value, time.Now().Add(ttl)
-	} else {
-		if v.value != nil && (len(dirty) > 0 && dirty[0]) {
-			return v.value.(T), nil
+		if v.Async && (v.MaxStale <= 0 || now.Before(v.next.Add(v.MaxStale))) {
+			value = v.value.(T)
+			if v.call == nil && !now.Before(v.retry) {
+				v.load()
+			}
+			v.locker.Unlock()
+			return
 		}
 	}
+	c := v.call
+	if c == nil {
+		c = v.load()
+	}
+	v.locker.Unlock()
+
+	select {
+	case <-c.done:
+		value, err = c.value, c.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil && len(dirty) > 0 && dirty[0] {
+		v.locker.RLock()
+		if v.value != nil {
+			value, err = v.value.(T), nil
+		}
+		v.locker.RUnlock()
+	}
 	return
 }
 
@@ -51,7 +101,75 @@ func (v *Value[T]) MustGet(dirty ...bool) (value T) {
 	return value
 }
 
-// Reset clears internal cache value.
+// Reset clears internal cache value, result of loading in progress is discarded.
 func (v *Value[T]) Reset() {
-	v.value = nil
+	v.locker.Lock()
+	v.gen++
+	v.call = nil
+	v.value, v.next = nil, time.Time{}
+	v.retry, v.fails = time.Time{}, 0
+	v.locker.Unlock()
+}
+
+// load starts loading in a new goroutine, the caller must hold the locker.
+func (v *Value[T]) load() *loadCall[T] {
+	c := &loadCall[T]{gen: v.gen, done: make(chan struct{})}
+	v.call = c
+	go func() {
+		defer close(c.done)
+		defer func() {
+			if e := recover(); e != nil {
+				c.err = errors.Convert(e)
+			}
+			v.update(c)
+		}()
+		c.value, c.err = v.Load()
+	}()
+	return c
+}
+
+func (v *Value[T]) update(c *loadCall[T]) {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+
+	if c.gen != v.gen {
+		// Reset was called while loading, the result may be stale
+		return
+	}
+
+	now := time.Now()
+	if c.err == nil {
+		v.value, v.next = c.value, now.Add(v.ttl(true))
+		v.retry, v.fails = time.Time{}, 0
+	} else {
+		v.fails++
+		v.retry = now.Add(v.backoff())
+	}
+	v.call = nil
+}
+
+func (v *Value[T]) ttl(jitter bool) time.Duration {
+	ttl := v.TTL
+	if ttl == 0 {
+		ttl = time.Hour
+	}
+	if jitter && v.Jitter > 0 && v.Jitter < 1 {
+		ttl += time.Duration((rand.Float64()*2 - 1) * v.Jitter * float64(ttl))
+	}
+	return ttl
+}
+
+func (v *Value[T]) backoff() time.Duration {
+	d := v.Backoff
+	if d <= 0 {
+		d = time.Second
+	}
+	max := v.ttl(false)
+	for i := 1; i < v.fails && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
 }
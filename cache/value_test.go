@@ -1,7 +1,9 @@
 package cache_test
 
 import (
+	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -38,3 +40,82 @@ func TestValue(t *testing.T) {
 
 	v.Reset()
 }
+
+func TestValue_Async(t *testing.T) {
+	var i int32
+	v := cache.Value[int32]{
+		TTL:   50 * time.Millisecond,
+		Async: true,
+		Load: func() (int32, error) {
+			time.Sleep(20 * time.Millisecond)
+			return atomic.AddInt32(&i, 1), nil
+		},
+	}
+
+	value, err := v.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), value)
+
+	time.Sleep(60 * time.Millisecond)
+
+	// stale value is returned while refreshing in background
+	value, err = v.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), value)
+
+	time.Sleep(40 * time.Millisecond)
+
+	value, err = v.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), value)
+}
+
+func TestValue_GetContext(t *testing.T) {
+	v := cache.Value[int]{
+		Load: func() (int, error) {
+			time.Sleep(100 * time.Millisecond)
+			return 1, nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := v.GetContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	value, err := v.GetContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, value)
+}
+
+func TestValue_Reset(t *testing.T) {
+	var i int32
+	v := cache.Value[int32]{
+		Load: func() (int32, error) {
+			n := atomic.AddInt32(&i, 1)
+			if n == 1 {
+				time.Sleep(50 * time.Millisecond)
+			}
+			return n, nil
+		},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = v.Get()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// loading started before Reset must not overwrite the value loaded after it
+	v.Reset()
+	value, err := v.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), value)
+
+	<-done
+	value, err = v.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), value)
+}
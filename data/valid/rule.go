@@ -74,9 +74,18 @@ const (
 type Context struct {
 	Value    reflect.Value
 	Info     *reflect.StructField
+	path     string // path of field, the same as Error.Field
 	messages map[string]string
 }
 
+// Name returns the field name used in messages, it is the same as `Error.Field`.
+func (c *Context) Name() string {
+	if c.path != "" {
+		return c.path
+	}
+	return c.Info.Name
+}
+
 type Argument struct {
 	Value       string
 	Left, Right ArgumentFlag
@@ -397,7 +406,7 @@ func assertKind(rule string, ctx *Context, value reflect.Value, kinds ...reflect
 		case "rule":
 			return rule
 		case "name":
-			return ctx.Info.Name
+			return ctx.Name()
 		case "type":
 			return value.Type().String()
 		default:
@@ -425,7 +434,7 @@ func failed(name string, ctx *Context, arg *Argument, value interface{}) error {
 	return errors.New(os.Expand(msg, func(name string) string {
 		switch name {
 		case "name":
-			return ctx.Info.Name
+			return ctx.Name()
 		case "value":
 			return fmt.Sprint(value)
 		case "arg":
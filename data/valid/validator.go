@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/cuigh/auxo/errors"
 	"github.com/cuigh/auxo/ext/reflects"
@@ -30,7 +32,15 @@ func Validate(i interface{}) error {
 	return validator.Validate(i)
 }
 
+// ValidateAll checks value of struct like Validate, but it reports all invalid fields, see Validator.All.
+func ValidateAll(i interface{}) error {
+	return validator.ValidateAll(i)
+}
+
+// Error is the failure of validating a field.
 type Error struct {
+	// Field is the name of field. In ValidateAll mode, it is the path of field like `address.city` or
+	// `items[0].name`, and names are taken from `json` tag if present.
 	Field string
 	Rule  string
 	cause error
@@ -40,13 +50,55 @@ func (e *Error) Cause() error {
 	return e.cause
 }
 
+// Message returns the prompt message of rule.
+func (e *Error) Message() string {
+	return e.cause.Error()
+}
+
+// Fields converts error to field errors which can be carried by `errors.CodedError`.
+func (e *Error) Fields() []errors.FieldError {
+	return []errors.FieldError{{Field: e.Field, Rule: e.Rule, Message: e.Message()}}
+}
+
 func (e *Error) Error() string {
 	return fmt.Sprintf("failed to validate field `%s` with rule `%s`: %v", e.Field, e.Rule, e.cause)
 }
 
+// Errors holds failures of all invalid fields, only the first failed rule of each field is reported.
+type Errors []*Error
+
+// Unwrap returns the first *Error, so `errors.As` works with both Errors and *Error.
+func (es Errors) Unwrap() error {
+	if len(es) == 0 {
+		return nil
+	}
+	return es[0]
+}
+
+func (es Errors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Fields converts errors to field errors which can be carried by `errors.CodedError`.
+func (es Errors) Fields() []errors.FieldError {
+	fields := make([]errors.FieldError, len(es))
+	for i, e := range es {
+		fields[i] = errors.FieldError{Field: e.Field, Rule: e.Rule, Message: e.Message()}
+	}
+	return fields
+}
+
 // Validator is a struct data validator.
 type Validator struct {
-	Tag      string
+	Tag string
+	// All makes Validate report all invalid fields as Errors, fields are named by their paths with `json` tag names,
+	// it is suitable for API responses like problem details.
+	// Optional. Default value false returns *Error of the first invalid field, which is named by struct field name.
+	All      bool
 	rules    map[string]Rule
 	messages map[string]string
 }
@@ -67,13 +119,41 @@ func (v *Validator) Prompt(name, msg string) {
 	v.messages[name] = msg
 }
 
-// Validate checks value of struct is available.
+// Validate checks value of struct is available, it returns *Error of the first invalid field,
+// or Errors of all invalid fields if All is true.
 func (v *Validator) Validate(i interface{}) error {
+	if v.All {
+		return v.ValidateAll(i)
+	}
+
+	value := reflects.Indirect(reflect.ValueOf(i))
+	if value.Kind() != reflect.Struct {
+		return errors.New("valid: target value must be a struct")
+	}
+	return v.validate(value, "", nil)
+}
+
+// ValidateAll checks value of struct is available, it returns Errors with all invalid fields,
+// or other error if rules are misconfigured.
+func (v *Validator) ValidateAll(i interface{}) error {
 	value := reflects.Indirect(reflect.ValueOf(i))
 	if value.Kind() != reflect.Struct {
 		return errors.New("valid: target value must be a struct")
 	}
 
+	var errs Errors
+	if err := v.validate(value, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate checks fields of struct, it stops at the first invalid field if errs is nil,
+// otherwise failures are collected into errs.
+func (v *Validator) validate(value reflect.Value, prefix string, errs *Errors) error {
 	key := v.Tag
 	if key == "" {
 		key = Tag
@@ -86,24 +166,44 @@ func (v *Validator) Validate(i interface{}) error {
 			return nil
 		}
 
-		rules, err := v.parseRules(fi.Tag.Get(key))
+		rules, err := ParseRules(fi.Tag.Get(key))
 		if err != nil {
 			return err
 		}
 
-		if len(rules) == 0 {
-			fv = reflects.Indirect(fv)
-			if fv.Kind() == reflect.Struct && fv.IsValid() {
-				return v.Validate(fv.Interface())
+		if errs == nil {
+			if len(rules) == 0 {
+				fv = reflects.Indirect(fv)
+				if fv.Kind() == reflect.Struct && fv.IsValid() {
+					return v.validate(fv, "", nil)
+				}
+				return nil
+			}
+
+			ctx.Value, ctx.Info, ctx.path = fv, fi, ""
+			for name, info := range rules {
+				if r := v.getRule(name); r != nil {
+					if err = r(ctx, &info); err != nil {
+						return &Error{Field: fi.Name, Rule: name, cause: err}
+					}
+				} else {
+					return errors.New("unknown rule: " + name)
+				}
 			}
 			return nil
 		}
 
-		ctx.Value, ctx.Info = fv, fi
+		path := prefix + fieldName(fi)
+		if len(rules) == 0 {
+			return v.validateElem(fv, path, errs)
+		}
+
+		ctx.Value, ctx.Info, ctx.path = fv, fi, path
 		for name, info := range rules {
 			if r := v.getRule(name); r != nil {
 				if err = r(ctx, &info); err != nil {
-					return &Error{Field: fi.Name, Rule: name, cause: err}
+					*errs = append(*errs, &Error{Field: path, Rule: name, cause: err})
+					break
 				}
 			} else {
 				return errors.New("unknown rule: " + name)
@@ -113,6 +213,37 @@ func (v *Validator) Validate(i interface{}) error {
 	})
 }
 
+// validateElem validates nested struct, or structs in slice and array.
+func (v *Validator) validateElem(fv reflect.Value, path string, errs *Errors) error {
+	fv = reflects.Indirect(fv)
+	switch fv.Kind() {
+	case reflect.Struct:
+		return v.validate(fv, path+".", errs)
+	case reflect.Slice, reflect.Array:
+		if t := fv.Type().Elem(); t.Kind() != reflect.Struct && (t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct) {
+			return nil
+		}
+		for i := 0; i < fv.Len(); i++ {
+			if err := v.validateElem(fv.Index(i), path+"["+strconv.Itoa(i)+"]", errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fieldName(fi *reflect.StructField) string {
+	if tag := fi.Tag.Get("json"); tag != "" {
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			tag = tag[:i]
+		}
+		if tag != "" && tag != "-" {
+			return tag
+		}
+	}
+	return fi.Name
+}
+
 func (v *Validator) getRule(name string) Rule {
 	if v.rules != nil {
 		if r, ok := v.rules[name]; ok {
@@ -122,7 +253,8 @@ func (v *Validator) getRule(name string) Rule {
 	return rules[name]
 }
 
-func (v *Validator) parseRules(tag string) (rules map[string]Argument, err error) {
+// ParseRules parses rules from a tag value like `required,length[1~5),regex(\d+)`.
+func ParseRules(tag string) (rules map[string]Argument, err error) {
 	// tag: length[1,5),regex(\d+),ip,width(5)
 	const (
 		stateName = 0
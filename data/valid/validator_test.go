@@ -1,6 +1,7 @@
 package valid_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/cuigh/auxo/data/valid"
@@ -191,3 +192,49 @@ func TestRegex(t *testing.T) {
 		}
 	}
 }
+
+func TestErrors(t *testing.T) {
+	type Item struct {
+		Name string `json:"name" valid:"required"`
+	}
+	v := &struct {
+		Name  string  `json:"name,omitempty" valid:"required"`
+		Age   int     `valid:"range[0~150]"`
+		Items []*Item `json:"items"`
+	}{
+		Age:   200,
+		Items: []*Item{{Name: "a"}, {}},
+	}
+
+	// the first invalid field is reported with struct field name by default
+	err := valid.Validate(v)
+	assert.Error(t, err)
+	e, ok := err.(*valid.Error)
+	assert.True(t, ok)
+	assert.Equal(t, "Name", e.Field)
+	assert.Equal(t, "field `Name` is required", e.Message())
+	assert.Equal(t, 1, len(e.Fields()))
+
+	err = valid.ValidateAll(v)
+	assert.Error(t, err)
+	errs, ok := err.(valid.Errors)
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(errs))
+	assert.Equal(t, "name", errs[0].Field)
+	assert.Equal(t, "required", errs[0].Rule)
+	assert.Equal(t, "Age", errs[1].Field)
+	assert.Equal(t, "items[1].name", errs[2].Field)
+
+	fields := errs.Fields()
+	assert.Equal(t, "field `name` is required", fields[0].Message)
+	assert.Equal(t, "field `items[1].name` is required", fields[2].Message)
+
+	// errors.As finds the first *Error of Errors
+	e = nil
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "name", e.Field)
+
+	err = (&valid.Validator{All: true}).Validate(v)
+	_, ok = err.(valid.Errors)
+	assert.True(t, ok)
+}
@@ -43,6 +43,7 @@ type DB interface {
 	Prepare(ctx context.Context, query string) (*Stmt, error)
 	Stmt(name string, b func() string) (*Stmt, error)
 	Transact(ctx context.Context, fn func(tx TX) error, opts ...*sql.TxOptions) (err error)
+	Ping(ctx context.Context) error
 }
 
 type database struct {
@@ -59,6 +60,11 @@ func (d *database) Database() string {
 	return d.opts.Name
 }
 
+// Ping verifies a connection to the database is still alive.
+func (d *database) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
 func (d *database) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	if d.opts.Trace.Enabled {
 		defer d.trace(query, args, time.Now())
@@ -21,6 +21,9 @@ const (
 var (
 	Nil = redis.Nil
 	f   = new(factory)
+
+	// NewStringResult returns a StringCmd initialised with val and err, it is useful for wrapping batch results.
+	NewStringResult = redis.NewStringResult
 )
 
 type Client = redis.Cmdable
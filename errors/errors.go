@@ -109,9 +109,17 @@ func Coded(code int32, msg string, detail ...string) *CodedError {
 }
 
 type CodedError struct {
-	Code    int32  `json:"code"`
-	Message string `json:"message,omitempty"`
-	Detail  string `json:"detail,omitempty"`
+	Code    int32        `json:"code"`
+	Message string       `json:"message,omitempty"`
+	Detail  string       `json:"detail,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes why a field of input is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
 }
 
 func (e *CodedError) Error() string {
@@ -6,13 +6,18 @@ require (
 	github.com/CloudyKit/jet/v6 v6.0.2
 	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/abronan/valkeyrie v0.0.0-20190822142731-f2e1850dc905
+	github.com/andybalholm/brotli v1.0.6
 	github.com/go-ldap/ldap/v3 v3.2.4
 	github.com/go-redis/redis v6.15.5+incompatible
 	github.com/go-sql-driver/mysql v1.4.1
+	github.com/golang-jwt/jwt/v5 v5.2.2
+	github.com/golang/snappy v0.0.1
+	github.com/gorilla/websocket v1.5.0
 	github.com/json-iterator/go v1.1.12
 	github.com/opentracing/opentracing-go v1.1.0
 	github.com/prometheus/client_golang v1.11.1
 	github.com/uber/jaeger-client-go v2.19.0+incompatible
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	go.mongodb.org/mongo-driver v1.12.0
 	golang.org/x/crypto v0.1.0
 	google.golang.org/protobuf v1.28.0
@@ -52,9 +57,7 @@ require (
 	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
-	github.com/golang/snappy v0.0.1 // indirect
 	github.com/google/uuid v1.3.0 // indirect
-	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/hashicorp/consul/api v1.1.0 // indirect
 	github.com/hashicorp/go-cleanhttp v0.5.1 // indirect
 	github.com/hashicorp/go-immutable-radix v1.0.0 // indirect
@@ -92,6 +95,7 @@ require (
 	github.com/urfave/cli v1.22.2 // indirect
 	github.com/vishvananda/netlink v1.1.1-0.20210330154013-f5de75959ad5 // indirect
 	github.com/vishvananda/netns v0.0.0-20210104183010-2eb08e3e575f // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
 	github.com/xdg-go/scram v1.1.2 // indirect
 	github.com/xdg-go/stringprep v1.0.4 // indirect
@@ -418,6 +418,12 @@ func (c *Client) getNode() (n *Node, err error) {
 	return
 }
 
+// Ping checks whether a node is available, it dials the node if it isn't connected yet.
+func (c *Client) Ping() error {
+	_, err := c.getNode()
+	return err
+}
+
 func (c *Client) Close() {
 	for _, n := range c.nodes {
 		n.Close()
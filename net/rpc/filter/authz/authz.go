@@ -0,0 +1,73 @@
+// Package authz provides an authorization filter of rpc server with the policy engine of `security/rbac`,
+// so web and rpc share one permission model.
+//
+//	s.Use(authz.Server(engine, authz.Rules{
+//		"Order.Delete": "perm:order.delete",
+//		"Order.*":      "perm:order.read",
+//		"Health.*":     "*",
+//	}))
+package authz
+
+import (
+	"strings"
+
+	"github.com/cuigh/auxo/log"
+	"github.com/cuigh/auxo/net/rpc"
+	"github.com/cuigh/auxo/security/rbac"
+)
+
+const PkgName = "auxo.net.rpc.filter.authz"
+
+// Rules maps action patterns to authorize rules. A pattern is an action name like `Order.Create`,
+// or a service wildcard like `Order.*`, or `*` for all actions. Exact names take precedence over wildcards.
+type Rules map[string]string
+
+// Options represents options of the filter.
+type Options struct {
+	// Default is the rule of actions not matched by Rules.
+	// Optional. Default value "?", which means only authenticated users can call.
+	Default string
+}
+
+// Server returns a filter which authorizes calls with the engine, user is set by authentication filters.
+func Server(e *rbac.Engine, rules Rules, opts ...Options) rpc.SFilter {
+	var o Options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Default == "" {
+		o.Default = rbac.RuleAuthenticated
+	}
+	logger := log.Get(PkgName)
+
+	return func(next rpc.SHandler) rpc.SHandler {
+		return func(c rpc.Context) (interface{}, error) {
+			name := c.Action().Name()
+			d := e.Explain(c.User(), rules.find(name, o.Default))
+			if d.Allowed {
+				return next(c)
+			}
+
+			logger.Debugf("authz > call %s is %s", name, d)
+			if !d.Authenticated {
+				return nil, rpc.NewError(rpc.StatusUnauthorized, "rpc: %s requires authentication", name)
+			}
+			return nil, rpc.NewError(rpc.StatusPermissionDenied, "rpc: permission denied: %s", name)
+		}
+	}
+}
+
+func (r Rules) find(name, def string) string {
+	if rule, ok := r[name]; ok {
+		return rule
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if rule, ok := r[name[:i]+".*"]; ok {
+			return rule
+		}
+	}
+	if rule, ok := r["*"]; ok {
+		return rule
+	}
+	return def
+}
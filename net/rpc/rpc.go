@@ -61,6 +61,9 @@ const (
 
 	// StatusNilResult indicates return value is nil.
 	StatusNilResult StatusCode = 12
+
+	// StatusPermissionDenied indicates client doesn't have permission to call the method.
+	StatusPermissionDenied StatusCode = 13
 )
 
 var (
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type acceptItem struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses headers like `Accept` and `Accept-Encoding`, items are sorted by quality descending.
+func parseAccept(header string) []acceptItem {
+	var items []acceptItem
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		item := acceptItem{value: part, q: 1}
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			item.value = strings.TrimSpace(part[:i])
+			for _, param := range strings.Split(part[i+1:], ";") {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						item.q = q
+					}
+				}
+			}
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+	return items
+}
+
+// AcceptEncoding returns the best encoding of supported for `Accept-Encoding` header.
+// If client accepts several encodings with the same quality, the order of supported is respected.
+// It returns an empty string if none is acceptable.
+func AcceptEncoding(header string, supported ...string) (best string) {
+	qs := make(map[string]float64)
+	for _, item := range parseAccept(header) {
+		qs[strings.ToLower(item.value)] = item.q
+	}
+
+	var bestQ float64
+	for _, s := range supported {
+		q, ok := qs[s]
+		if !ok {
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = s, q
+		}
+	}
+	return
+}
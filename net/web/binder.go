@@ -1,8 +1,6 @@
 package web
 
 import (
-	"encoding/json"
-	"encoding/xml"
 	"io"
 	"net/http"
 	"reflect"
@@ -43,13 +41,20 @@ func (b *binder) Bind(ctx Context, i interface{}) (err error) {
 		return b.unmarshal(ctx, i)
 	case strings.HasPrefix(ct, "multipart/form-data"):
 		return b.unmarshal(ctx, i)
-	case strings.HasPrefix(ct, "application/json"):
-		return json.NewDecoder(ctx.Request().Body).Decode(i)
-	case strings.HasPrefix(ct, "application/xml"):
-		return xml.NewDecoder(ctx.Request().Body).Decode(i)
-	default:
-		return errors.New("unsupported content type")
 	}
+
+	if encoder := ctx.Server().Encoders.Get(ct); encoder != nil {
+		max := int64(ctx.Server().cfg.MaxBodySize)
+		if ctx.Request().ContentLength > max {
+			return NewError(http.StatusRequestEntityTooLarge)
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(ctx.Response(), ctx.Request().Body, max))
+		if err != nil {
+			return err
+		}
+		return encoder.Unmarshal(data, i)
+	}
+	return errors.New("unsupported content type")
 }
 
 func (b *binder) unmarshal(ctx Context, v interface{}) error {
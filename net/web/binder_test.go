@@ -47,6 +47,39 @@ func TestBindXML(t *testing.T) {
 	bindUser(t, r)
 }
 
+func TestBindYAML(t *testing.T) {
+	const content = "id: 1\nname: test\n"
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(content))
+	r.Header.Set(web.HeaderContentType, web.MIMEApplicationYAML)
+	bindUser(t, r)
+}
+
+func TestBindUnsupported(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("test"))
+	r.Header.Set(web.HeaderContentType, "application/unknown")
+	ctx := web.Default().AcquireContext(nil, r)
+	assert.Error(t, ctx.Bind(&TestUser{}))
+}
+
+func TestBindTooLarge(t *testing.T) {
+	s := web.New(&web.Options{MaxBodySize: 16})
+	content := `{"id":1,"name":"` + strings.Repeat("x", 16) + `"}`
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(content))
+	r.Header.Set(web.HeaderContentType, web.MIMEApplicationJSON)
+	err := s.AcquireContext(httptest.NewRecorder(), r).Bind(&TestUser{})
+	e, ok := err.(*web.Error)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, e.Status())
+
+	// body without Content-Length is limited while reading
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(content))
+	r.Header.Set(web.HeaderContentType, web.MIMEApplicationJSON)
+	r.ContentLength = -1
+	assert.Error(t, s.AcquireContext(httptest.NewRecorder(), r).Bind(&TestUser{}))
+}
+
 func TestBindPtrValue(t *testing.T) {
 	v := &struct {
 		ID *int
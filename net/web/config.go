@@ -30,6 +30,8 @@ type Options struct {
 	Entries               []Entry
 	RedirectTrailingSlash bool
 	MethodNotAllowed      bool
+	IgnoreCase            bool // match static segments of route path case-insensitively
+	DecodeParam           bool // unescape path parameter values
 	ReadTimeout           time.Duration
 	ReadHeaderTimeout     time.Duration
 	WriteTimeout          time.Duration
@@ -38,6 +40,10 @@ type Options struct {
 	MaxBodySize           size.Size
 	Authorize             string   // default authorize
 	IndexPages            []string // static index pages, default: index.html
+	Precompressed         bool     // serve precompressed .br/.gz siblings of static files
+	WebSocket             WebSocketOptions
+	TrustedProxies        []string      // CIDRs of proxies whose forwarding headers are trusted, e.g. 10.0.0.0/8
+	ShutdownDelay         time.Duration // time to keep serving after readiness fails on Close, 0 means the default of Server.DelayShutdown (5s with health endpoints), negative disables it
 	//IndexUrl              string
 	//LoginUrl              string
 	//UnauthorizedUrl       string
@@ -6,15 +6,17 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/cuigh/auxo/data"
+	"github.com/cuigh/auxo/errors"
 	"github.com/cuigh/auxo/log"
 	"github.com/cuigh/auxo/util/cast"
 )
@@ -33,8 +35,8 @@ type Requester interface {
 	// Scheme returns the HTTP protocol scheme, `http` or `https`.
 	Scheme() string
 
-	// RealIP returns the client's network address based on `X-Forwarded-For`
-	// or `X-Real-IP` request header.
+	// RealIP returns the client's network address based on `Forwarded`, `X-Forwarded-For`
+	// or `X-Real-IP` request header, headers are only trusted if request comes from `Options.TrustedProxies`.
 	RealIP() string
 
 	// IsAJAX returns true if this request is an AJAX request(XMLHttpRequest)
@@ -46,6 +48,12 @@ type Requester interface {
 	// P returns path parameter by name, it's an alias of Path method.
 	P(name string) string
 
+	// PathInt returns path parameter as int, it returns a 400 error if the value is not an integer.
+	PathInt(name string) (int, error)
+
+	// PathInt64 returns path parameter as int64, it returns a 400 error if the value is not an integer.
+	PathInt64(name string) (int64, error)
+
 	// PathNames returns path parameter names.
 	PathNames() []string
 
@@ -97,6 +105,10 @@ type Responser interface {
 	// Response returns `ResponseWriter`.
 	Response() ResponseWriter
 
+	// SetResponse replaces the underlying `http.ResponseWriter` of response, it is generally used by filters
+	// to wrap the response, e.g. compressing.
+	SetResponse(w http.ResponseWriter)
+
 	// Status set status code of response.
 	Status(code int) Responser
 
@@ -109,6 +121,10 @@ type Responser interface {
 	// SetContentType sets the 'Content-Type' header of response.
 	SetContentType(ct string) Responser
 
+	// Negotiate sends value with status code, it is encoded by the encoder of `Server.Encoders` which best
+	// matches `Accept` header. It returns a 406 error if no encoder is acceptable.
+	Negotiate(status int, value interface{}) error
+
 	// Render renders a template with data and sends a text/html response.
 	// Renderer must be registered using `Server.Renderer`.
 	Render(name string, data interface{}) error
@@ -138,6 +154,10 @@ type Responser interface {
 	// You can use `Context.SetContentType` to set content type.
 	Stream(r io.Reader, cd ...ContentDisposition) error
 
+	// SSE starts a Server-Sent Events response, heartbeat comments are sent every 15s by default.
+	// The stream is closed automatically when handler returns.
+	SSE(heartbeat ...time.Duration) *EventStream
+
 	// Content sends a response with the content of the file.
 	Content(file string, cd ...ContentDisposition) error
 
@@ -170,6 +190,12 @@ type Context interface {
 	// SetUser set user info of current visitor. Generally used by authentication filter.
 	SetUser(user User)
 
+	// Session returns the session of current visitor, it is nil if no session filter is applied.
+	Session() Session
+
+	// SetSession sets the session of current visitor. Generally used by session filter.
+	SetSession(session Session)
+
 	// Get retrieves data from the context.
 	Get(key string) interface{}
 
@@ -203,8 +229,10 @@ type context struct {
 	query      url.Values
 	handler    HandlerInfo
 	user       User
+	session    Session
 	data       data.Map
 	server     *Server
+	stream     *EventStream
 }
 
 func (c *context) Request() *http.Request {
@@ -219,6 +247,10 @@ func (c *context) Response() ResponseWriter {
 	return c.response
 }
 
+func (c *context) SetResponse(w http.ResponseWriter) {
+	c.response.ResponseWriter = w
+}
+
 func (c *context) IsAJAX() bool {
 	return c.request.Header.Get(HeaderXRequestedWith) == "XMLHttpRequest"
 }
@@ -249,15 +281,7 @@ func (c *context) Scheme() string {
 }
 
 func (c *context) RealIP() string {
-	ra := c.request.RemoteAddr
-	if ip := strings.TrimSpace(c.request.Header.Get(HeaderXRealIP)); ip != "" {
-		ra = ip
-	} else if ip := strings.TrimSpace(c.request.Header.Get(HeaderXForwardedFor)); ip != "" {
-		ra = ip
-	} else {
-		ra, _, _ = net.SplitHostPort(ra)
-	}
-	return ra
+	return realIP(c.request, c.server.trusted)
 }
 
 func (c *context) Route() string {
@@ -279,6 +303,22 @@ func (c *context) P(name string) string {
 	return c.Path(name)
 }
 
+func (c *context) PathInt(name string) (int, error) {
+	i, err := strconv.Atoi(c.Path(name))
+	if err != nil {
+		return 0, NewError(http.StatusBadRequest, "invalid path parameter: "+name)
+	}
+	return i, nil
+}
+
+func (c *context) PathInt64(name string) (int64, error) {
+	i, err := strconv.ParseInt(c.Path(name), 10, 64)
+	if err != nil {
+		return 0, NewError(http.StatusBadRequest, "invalid path parameter: "+name)
+	}
+	return i, nil
+}
+
 func (c *context) PathNames() []string {
 	return c.pathNames
 }
@@ -360,6 +400,14 @@ func (c *context) SetUser(user User) {
 	c.user = user
 }
 
+func (c *context) Session() Session {
+	return c.session
+}
+
+func (c *context) SetSession(session Session) {
+	c.session = session
+}
+
 func (c *context) Get(key string) interface{} {
 	if c.data == nil {
 		return nil
@@ -384,7 +432,14 @@ func (c *context) Bind(i interface{}, validate ...bool) (err error) {
 		if c.server.Validator == nil {
 			return ErrValidatorNotRegistered
 		}
-		err = c.server.Validator.Validate(i)
+		if err = c.server.Validator.Validate(i); err != nil {
+			// report invalid fields to client, see valid.Validator.All to report all of them
+			if fe, ok := err.(interface{ Fields() []errors.FieldError }); ok {
+				e := NewError(http.StatusBadRequest, "invalid request")
+				e.Fields = fe.Fields()
+				return e
+			}
+		}
 	}
 	return
 }
@@ -483,6 +538,20 @@ func (c *context) XMLBlob(b []byte) (err error) {
 	return
 }
 
+func (c *context) Negotiate(status int, value interface{}) error {
+	c.response.Header().Add(HeaderVary, HeaderAccept)
+	ct, encoder := c.server.Encoders.Negotiate(c.request.Header.Get(HeaderAccept))
+	if encoder == nil {
+		return ErrNotAcceptable
+	}
+
+	b, err := encoder.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.Status(status).SetContentType(ct).Data(b)
+}
+
 func (c *context) Data(b []byte, cd ...ContentDisposition) (err error) {
 	if len(cd) > 0 {
 		c.SetHeader(HeaderContentDisposition, fmt.Sprintf("%s; filename=%s", cd[0].Type, cd[0].Name))
@@ -499,6 +568,17 @@ func (c *context) Stream(r io.Reader, cd ...ContentDisposition) (err error) {
 	return
 }
 
+func (c *context) SSE(heartbeat ...time.Duration) *EventStream {
+	if c.stream == nil {
+		d := 15 * time.Second
+		if len(heartbeat) > 0 {
+			d = heartbeat[0]
+		}
+		c.stream = newEventStream(c, d)
+	}
+	return c.stream
+}
+
 func (c *context) Content(file string, cd ...ContentDisposition) error {
 	if len(cd) > 0 {
 		c.SetHeader(HeaderContentDisposition, fmt.Sprintf("%s; filename=%s", cd[0].Type, cd[0].Name))
@@ -590,6 +670,8 @@ func (c *context) Reset(r *http.Request, w http.ResponseWriter) {
 	c.query = nil
 	c.data = nil
 	c.user = nil
+	c.session = nil
+	c.stream = nil
 	c.request = r
 	c.response.reset(w)
 	c.handler = notFound
@@ -605,7 +687,7 @@ func newContextPool(s *Server) *contextPool {
 		return &context{
 			server:     s,
 			response:   &responseWriter{server: s},
-			pathValues: make([]string, s.router.MaxParam()),
+			pathValues: make([]string, s.maxParam()),
 		}
 	}
 	return p
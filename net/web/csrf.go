@@ -0,0 +1,38 @@
+package web
+
+import "html/template"
+
+// CSRFKey is the context key of CSRFTokener, it is set by CSRF filter.
+const CSRFKey = "auxo.csrf"
+
+// CSRFTokener generates tokens for the current request, it is stored in context by CSRF filter,
+// so renderers can use tokens without depending on package filter.
+type CSRFTokener interface {
+	// Token returns a token which should be sent back with unsafe requests.
+	Token() string
+	// Field returns a hidden input element with token for HTML forms.
+	Field() template.HTML
+}
+
+// CSRFToken returns a token for the current request, it returns an empty string if CSRF filter is not applied.
+func CSRFToken(ctx Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if t, ok := ctx.Get(CSRFKey).(CSRFTokener); ok {
+		return t.Token()
+	}
+	return ""
+}
+
+// CSRFField returns a hidden input element with token for HTML forms, it returns an empty string if
+// CSRF filter is not applied.
+func CSRFField(ctx Context) template.HTML {
+	if ctx == nil {
+		return ""
+	}
+	if t, ok := ctx.Get(CSRFKey).(CSRFTokener); ok {
+		return t.Field()
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+
+	"github.com/cuigh/auxo/encoding/yaml"
+	"github.com/cuigh/auxo/errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Encoder marshals response values and unmarshals request bodies of a media type.
+type Encoder interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+type encoderEntry struct {
+	mediaType   string
+	contentType string
+	encoder     Encoder
+}
+
+// Encoders is a registry of encoders which is used by content negotiation and request binding.
+type Encoders struct {
+	entries []*encoderEntry
+}
+
+// NewEncoders creates a registry with JSON, XML, YAML, MessagePack and Protocol Buffers encoders.
+func NewEncoders() *Encoders {
+	e := &Encoders{}
+	e.Register(MIMEApplicationJSONCharsetUTF8, jsonEncoder{})
+	e.Register(MIMEApplicationXMLCharsetUTF8, xmlEncoder{})
+	e.Register(MIMETextXMLCharsetUTF8, xmlEncoder{})
+	e.Register(MIMEApplicationYAMLCharsetUTF8, yamlEncoder{})
+	e.Register("application/x-yaml; charset=UTF-8", yamlEncoder{})
+	e.Register(MIMEApplicationMsgpack, msgpackEncoder{})
+	e.Register("application/x-msgpack", msgpackEncoder{})
+	e.Register(MIMEApplicationProtobuf, protobufEncoder{})
+	e.Register("application/x-protobuf", protobufEncoder{})
+	return e
+}
+
+// Register adds an encoder for content type like `application/json; charset=UTF-8`, the existing one is replaced.
+// The order of registration is the preference of server when client accepts several types equally.
+func (e *Encoders) Register(contentType string, encoder Encoder) {
+	mt := mediaType(contentType)
+	for _, entry := range e.entries {
+		if entry.mediaType == mt {
+			entry.contentType, entry.encoder = contentType, encoder
+			return
+		}
+	}
+	e.entries = append(e.entries, &encoderEntry{mediaType: mt, contentType: contentType, encoder: encoder})
+}
+
+// Get returns the encoder of media type, it returns nil if not found.
+func (e *Encoders) Get(contentType string) Encoder {
+	mt := mediaType(contentType)
+	for _, entry := range e.entries {
+		if entry.mediaType == mt {
+			return entry.encoder
+		}
+	}
+	return nil
+}
+
+// Negotiate returns the content type and encoder which best match `Accept` header.
+// The first registered encoder is returned if header is empty, and nil is returned if none is acceptable.
+func (e *Encoders) Negotiate(accept string) (contentType string, encoder Encoder) {
+	if len(e.entries) == 0 {
+		return
+	}
+	if accept == "" {
+		return e.entries[0].contentType, e.entries[0].encoder
+	}
+
+	for _, item := range parseAccept(accept) {
+		if item.q <= 0 {
+			break
+		}
+
+		mt := strings.ToLower(item.value)
+		for _, entry := range e.entries {
+			if mt == "*/*" || mt == entry.mediaType ||
+				(strings.HasSuffix(mt, "/*") && strings.HasPrefix(entry.mediaType, mt[:len(mt)-1])) {
+				return entry.contentType, entry.encoder
+			}
+		}
+	}
+	return
+}
+
+func mediaType(ct string) string {
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(ct))
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonEncoder) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+type xmlEncoder struct{}
+
+func (xmlEncoder) Marshal(v interface{}) ([]byte, error) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+func (xmlEncoder) Unmarshal(b []byte, v interface{}) error {
+	return xml.Unmarshal(b, v)
+}
+
+type yamlEncoder struct{}
+
+func (yamlEncoder) Marshal(v interface{}) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+
+func (yamlEncoder) Unmarshal(b []byte, v interface{}) error {
+	return yaml.Unmarshal(b, v)
+}
+
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackEncoder) Unmarshal(b []byte, v interface{}) error {
+	return msgpack.Unmarshal(b, v)
+}
+
+type protobufEncoder struct{}
+
+func (protobufEncoder) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Marshal(m)
+	}
+	return nil, errors.Format("value of type %T is not a proto.Message", v)
+}
+
+func (protobufEncoder) Unmarshal(b []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(b, m)
+	}
+	return errors.Format("value of type %T is not a proto.Message", v)
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cuigh/auxo/test/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestEncoders_Negotiate(t *testing.T) {
+	e := NewEncoders()
+	cases := []struct {
+		Accept      string
+		ContentType string
+	}{
+		{"", MIMEApplicationJSONCharsetUTF8},
+		{"*/*", MIMEApplicationJSONCharsetUTF8},
+		{"application/xml", MIMEApplicationXMLCharsetUTF8},
+		{"text/*", MIMETextXMLCharsetUTF8},
+		{"application/json;q=0.5, application/yaml", MIMEApplicationYAMLCharsetUTF8},
+		{"application/x-msgpack", "application/x-msgpack"},
+		{"text/html, application/json;q=0", ""},
+	}
+	for _, c := range cases {
+		ct, _ := e.Negotiate(c.Accept)
+		assert.Equal(t, c.ContentType, ct, "accept: %s", c.Accept)
+	}
+}
+
+func TestContext_Negotiate(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	s := Default()
+	s.Post("/user", func(ctx Context) error {
+		return ctx.Negotiate(http.StatusCreated, user{Name: "test"})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/user", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{"name":"test"}`, rec.Body.String())
+	assert.Equal(t, HeaderAccept, rec.Header().Get(HeaderVary))
+
+	req = httptest.NewRequest(http.MethodPost, "/user", nil)
+	req.Header.Set(HeaderAccept, MIMEApplicationMsgpack)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, MIMEApplicationMsgpack, rec.Header().Get(HeaderContentType))
+	u := user{}
+	assert.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), &u))
+	assert.Equal(t, "test", u.Name)
+
+	req = httptest.NewRequest(http.MethodPost, "/user", nil)
+	req.Header.Set(HeaderAccept, MIMETextHTML)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+}
@@ -1,9 +1,12 @@
 package web
 
 import (
+	"encoding/json"
 	"net/http"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/cuigh/auxo/data"
 	"github.com/cuigh/auxo/errors"
@@ -15,8 +18,10 @@ var (
 	ErrForbidden              = NewError(http.StatusForbidden)
 	ErrNotFound               = NewError(http.StatusNotFound)
 	ErrMethodNotAllowed       = NewError(http.StatusMethodNotAllowed)
+	ErrNotAcceptable          = NewError(http.StatusNotAcceptable)
 	ErrUnsupportedMediaType   = NewError(http.StatusUnsupportedMediaType)
 	ErrInternalServerError    = NewError(http.StatusInternalServerError)
+	ErrTimeout                = NewError(http.StatusServiceUnavailable, "request timeout")
 	ErrRendererNotRegistered  = errors.New("Renderer not registered")
 	ErrBinderNotRegistered    = errors.New("Binder not registered")
 	ErrValidatorNotRegistered = errors.New("Validator not registered")
@@ -64,12 +69,29 @@ func toError(err error) *Error {
 
 type ErrorHandlerFunc func(Context, error)
 
+// Problem is the problem details of RFC 7807, it is rendered as `application/problem+json`.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     int                 `json:"code,omitempty"`
+	Errors   []errors.FieldError `json:"errors,omitempty"`
+}
+
 type ErrorHandler struct {
-	Detail  bool
-	Default ErrorHandlerFunc
-	errors  map[reflect.Type]ErrorHandlerFunc
-	codes   map[int]ErrorHandlerFunc
-	types   map[string]ErrorHandlerFunc
+	Detail bool
+	// Problem renders errors of JSON requests as RFC 7807 problem details,
+	// otherwise problem details are only rendered if client accepts `application/problem+json` explicitly.
+	Problem bool
+	// ProblemType is the base URI of problem types, the type of a problem is ProblemType + status, like
+	// `https://example.com/problems/400`. Type is `about:blank` if it is empty.
+	ProblemType string
+	Default     ErrorHandlerFunc
+	errors      map[reflect.Type]ErrorHandlerFunc
+	codes       map[int]ErrorHandlerFunc
+	types       map[string]ErrorHandlerFunc
 }
 
 func (h *ErrorHandler) OnCode(code int, fn ErrorHandlerFunc) {
@@ -131,22 +153,43 @@ func (h *ErrorHandler) handle(c Context, err error) {
 
 	// default handler
 	if e, ok := err.(*Error); ok {
-		h.handleError(c, e.Status(), 0, e.Message, e.Detail)
+		h.handleError(c, e.Status(), 0, e.Message, e.Detail, e.Fields)
 	} else if e, ok := err.(*errors.CodedError); ok {
-		h.handleError(c, http.StatusInternalServerError, int(e.Code), e.Message, e.Detail)
+		h.handleError(c, http.StatusInternalServerError, int(e.Code), e.Message, e.Detail, e.Fields)
 	} else {
-		h.handleError(c, http.StatusInternalServerError, 0, err.Error(), "")
+		h.handleError(c, http.StatusInternalServerError, 0, err.Error(), "", nil)
 	}
 }
 
-func (h *ErrorHandler) handleError(c Context, status, code int, msg, detail string) {
+func (h *ErrorHandler) handleError(c Context, status, code int, msg, detail string, fields []errors.FieldError) {
 	if c.Request().Method == http.MethodHead {
 		h.logError(c, c.Status(code).Empty())
 		return
 	}
 
 	ct := c.ContentType()
-	if ct == MIMEApplicationJSON {
+	if h.acceptProblem(c, ct) {
+		p := &Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   msg,
+			Instance: c.Request().URL.RequestURI(),
+			Code:     code,
+			Errors:   fields,
+		}
+		if h.ProblemType != "" {
+			p.Type = strings.TrimSuffix(h.ProblemType, "/") + "/" + strconv.Itoa(status)
+		}
+		if h.Detail && detail != "" {
+			p.Detail = msg + ": " + detail
+		}
+		b, err := json.Marshal(p)
+		if err == nil {
+			err = c.Status(status).SetContentType(MIMEApplicationProblemJSON).Data(b)
+		}
+		h.logError(c, err)
+	} else if ct == MIMEApplicationJSON {
 		m := data.Map{
 			"url":     c.Route(),
 			"code":    code,
@@ -155,6 +198,9 @@ func (h *ErrorHandler) handleError(c Context, status, code int, msg, detail stri
 		if h.Detail && detail != "" {
 			m["detail"] = detail
 		}
+		if len(fields) > 0 {
+			m["errors"] = fields
+		}
 		h.logError(c, c.Status(status).JSON(m))
 	} else {
 		if h.Detail && detail != "" {
@@ -164,6 +210,14 @@ func (h *ErrorHandler) handleError(c Context, status, code int, msg, detail stri
 	}
 }
 
+func (h *ErrorHandler) acceptProblem(c Context, ct string) bool {
+	accept := c.Header(HeaderAccept)
+	if strings.Contains(accept, MIMEApplicationProblemJSON) {
+		return true
+	}
+	return h.Problem && (ct == MIMEApplicationJSON || strings.Contains(accept, MIMEApplicationJSON))
+}
+
 func (h *ErrorHandler) logError(c Context, err error) {
 	if err != nil {
 		c.Logger().Error(err)
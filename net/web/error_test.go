@@ -0,0 +1,59 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cuigh/auxo/data/valid"
+	"github.com/cuigh/auxo/errors"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestErrorHandler_Problem(t *testing.T) {
+	type user struct {
+		Name  string `json:"name" valid:"required"`
+		Email string `json:"email" valid:"email"`
+	}
+
+	s := Default()
+	s.Validator = &valid.Validator{All: true}
+	s.ErrorHandler.ProblemType = "https://example.com/problems"
+	s.Post("/users", func(ctx Context) error {
+		return ctx.Bind(&user{}, true)
+	})
+	s.Get("/coded", func(ctx Context) error {
+		return errors.Coded(1001, "quota exceeded")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users?x=1", strings.NewReader(`{"email":"abc"}`))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	req.Header.Set(HeaderAccept, MIMEApplicationProblemJSON)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(HeaderContentType))
+	p := &Problem{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), p))
+	assert.Equal(t, "https://example.com/problems/400", p.Type)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), p.Title)
+	assert.Equal(t, "/users?x=1", p.Instance)
+	assert.Equal(t, 2, len(p.Errors))
+	assert.Equal(t, "name", p.Errors[0].Field)
+	assert.Equal(t, "email", p.Errors[1].Rule)
+
+	s.ErrorHandler.Problem = true
+	req = httptest.NewRequest(http.MethodGet, "/coded", nil)
+	req.Header.Set(HeaderAccept, MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	p = &Problem{}
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), p))
+	assert.Equal(t, 1001, p.Code)
+	assert.Equal(t, "quota exceeded", p.Detail)
+}
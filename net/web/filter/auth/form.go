@@ -15,7 +15,8 @@ type FormValidator func(ctx web.Context) (ticket string, err error)
 // FormIdentifier defines a function to identify user ticket.
 type FormIdentifier func(ticket string) web.User
 
-// Form implements form authentication.
+// Form implements form authentication. If a session filter is applied, the session ID is regenerated
+// on login to prevent session fixation.
 type Form struct {
 	CookieName        string // default '_u'
 	CookieDomain      string
@@ -81,6 +82,7 @@ func (f *Form) Login(validator FormValidator) web.HandlerFunc {
 			return err
 		}
 
+		regenerateSession(ctx)
 		f.renewTicket(ctx, ticket)
 
 		url := ctx.Q("from")
@@ -150,3 +152,10 @@ func (f *Form) renewTicket(ctx web.Context, ticket string) {
 	}
 	ctx.SetCookie(c)
 }
+
+// regenerateSession changes ID of current session if it exists, it should be called when user signs in.
+func regenerateSession(ctx web.Context) {
+	if s := ctx.Session(); s != nil {
+		s.Regenerate()
+	}
+}
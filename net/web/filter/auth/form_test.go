@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/security"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestForm_Login(t *testing.T) {
+	sess := &stubSession{}
+	f := NewForm(func(ticket string) web.User {
+		return security.NewUser(ticket, "admin")
+	})
+	s := newServer(f)
+	s.UseFunc(withSession(sess))
+	s.Post("/login", f.LoginForm(func(name, pwd string) (string, error) {
+		if name != "admin" || pwd != "pwd" {
+			return "", web.NewError(http.StatusUnauthorized)
+		}
+		return "1", nil
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/login?from=/home", nil)
+	req.PostForm = map[string][]string{"name": {"admin"}, "password": {"wrong"}}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, 0, sess.regenerated)
+
+	req = httptest.NewRequest(http.MethodPost, "/login?from=/home", nil)
+	req.PostForm = map[string][]string{"name": {"admin"}, "password": {"pwd"}}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/home", rec.Header().Get(web.HeaderLocation))
+	assert.Equal(t, 1, sess.regenerated)
+
+	cookie := findCookie(rec.Result().Cookies(), f.CookieName)
+	assert.NotNil(t, cookie)
+	assert.Equal(t, "1:admin", request(s, "/", []*http.Cookie{cookie}).Body.String())
+}
+
+// stubSession records regeneration, other methods of web.Session are not used in tests.
+type stubSession struct {
+	web.Session
+	regenerated int
+}
+
+func (s *stubSession) Regenerate() {
+	s.regenerated++
+}
+
+func withSession(sess web.Session) web.FilterFunc {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx web.Context) error {
+			ctx.SetSession(sess)
+			return next(ctx)
+		}
+	}
+}
+
+func request(s *web.Server, target string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, c := range cookies {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuigh/auxo/errors"
+)
+
+// KeySource provides keys for verifying token signatures.
+type KeySource interface {
+	// Key returns the verification key for key id and algorithm, kid may be empty.
+	Key(kid, alg string) (interface{}, error)
+}
+
+// KeySourceFunc is an adapter to allow the use of ordinary functions as KeySource.
+type KeySourceFunc func(kid, alg string) (interface{}, error)
+
+// Key implements KeySource interface.
+func (f KeySourceFunc) Key(kid, alg string) (interface{}, error) {
+	return f(kid, alg)
+}
+
+// StaticKey returns a KeySource which always returns key, it can be a []byte for HMAC,
+// a *rsa.PublicKey for RSA or a *ecdsa.PublicKey for ECDSA. Private keys are converted to public keys.
+func StaticKey(key interface{}) KeySource {
+	key = publicKey(key)
+	return KeySourceFunc(func(kid, alg string) (interface{}, error) {
+		return key, nil
+	})
+}
+
+// JWKS is a KeySource which loads keys from a JSON Web Key Set file or URL.
+// Keys are reloaded periodically and also when an unknown key id is met, so keys can be rotated without restart.
+// Lookups never wait for a periodic reload, which is done in background with the current keys still in use.
+type JWKS struct {
+	// Location is a file path or a HTTP(S) URL of key set.
+	Location string
+
+	// Refresh is the interval of reloading keys.
+	// Optional. Default value 1 hour.
+	Refresh time.Duration
+
+	// MinRefresh is the minimum interval of reloading keys when an unknown key id is met.
+	// Optional. Default value 1 minute.
+	MinRefresh time.Duration
+
+	// Client is used to fetch keys from URL.
+	// Optional. Default value http.Client with 10 seconds timeout.
+	Client *http.Client
+
+	locker sync.RWMutex
+	keys   map[string]interface{}
+	loaded time.Time
+	flight *jwksFlight
+}
+
+// jwksFlight is an in-progress loading, concurrent reloads share it.
+type jwksFlight struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKS creates a JWKS instance with location of key set.
+func NewJWKS(location string) *JWKS {
+	return &JWKS{Location: location}
+}
+
+// Key implements KeySource interface.
+func (s *JWKS) Key(kid, alg string) (interface{}, error) {
+	refresh, minRefresh := s.Refresh, s.MinRefresh
+	if refresh <= 0 {
+		refresh = time.Hour
+	}
+	if minRefresh <= 0 {
+		minRefresh = time.Minute
+	}
+
+	s.locker.RLock()
+	key, loaded, empty := s.find(kid, alg), s.loaded, s.keys == nil
+	s.locker.RUnlock()
+
+	if empty {
+		if err := s.reload(); err != nil {
+			return nil, err
+		}
+		key, loaded = s.lookup(kid, alg)
+	} else if time.Since(loaded) > refresh {
+		// refresh in background, current keys are used meanwhile
+		go s.reload()
+	}
+
+	if key == nil && time.Since(loaded) > minRefresh {
+		if err := s.reload(); err != nil {
+			return nil, err
+		}
+		key, _ = s.lookup(kid, alg)
+	}
+	if key == nil {
+		return nil, errors.Format("jwks: key '%s' not found", kid)
+	}
+	return key, nil
+}
+
+func (s *JWKS) lookup(kid, alg string) (interface{}, time.Time) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	return s.find(kid, alg), s.loaded
+}
+
+// find searches key in current keys, the caller must hold the lock.
+func (s *JWKS) find(kid, alg string) interface{} {
+	if kid != "" {
+		return s.keys[kid]
+	}
+
+	// token without kid can only match the unique key of the algorithm family
+	var found interface{}
+	for _, key := range s.keys {
+		if matchKey(key, alg) {
+			if found != nil {
+				return nil
+			}
+			found = key
+		}
+	}
+	return found
+}
+
+// reload loads keys and waits for it, only one loading is performed at a time.
+// Current keys are kept if loading fails.
+func (s *JWKS) reload() error {
+	s.locker.Lock()
+	f := s.flight
+	if f == nil {
+		f = &jwksFlight{done: make(chan struct{})}
+		s.flight = f
+		go s.load(f)
+	}
+	s.locker.Unlock()
+
+	<-f.done
+	return f.err
+}
+
+func (s *JWKS) load(f *jwksFlight) {
+	var keys map[string]interface{}
+	b, err := s.read()
+	if err == nil {
+		keys, err = parseJWKS(b)
+	}
+
+	s.locker.Lock()
+	s.loaded = time.Now()
+	if err == nil {
+		s.keys = keys
+	}
+	s.flight = nil
+	s.locker.Unlock()
+
+	f.err = err
+	close(f.done)
+}
+
+func (s *JWKS) read() ([]byte, error) {
+	if !strings.HasPrefix(s.Location, "http://") && !strings.HasPrefix(s.Location, "https://") {
+		return os.ReadFile(s.Location)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Get(s.Location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Format("jwks: failed to fetch keys: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// parseJWKS parses a key set, unsupported or invalid keys are ignored.
+func parseJWKS(b []byte) (map[string]interface{}, error) {
+	set := &struct {
+		Keys []*jwk `json:"keys"`
+	}{}
+	if err := json.Unmarshal(b, set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// providers often publish keys of other types (e.g. OKP), skip them instead of failing the whole set
+		if key, err := k.key(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k *jwk) key() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Format("jwks: unsupported curve '%s'", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, errors.Format("jwks: unsupported key type '%s'", k.Kty)
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// matchKey checks whether key can be used with algorithm alg.
+func matchKey(key interface{}, alg string) bool {
+	switch key.(type) {
+	case []byte:
+		return strings.HasPrefix(alg, "HS")
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(alg, "ES")
+	default:
+		return false
+	}
+}
+
+func publicKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		return k.(crypto.Signer).Public()
+	case string:
+		return []byte(k)
+	default:
+		return key
+	}
+}
@@ -0,0 +1,357 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuigh/auxo/errors"
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/security"
+	"github.com/cuigh/auxo/security/certify"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the claim set of a JWT token.
+type Claims = jwt.MapClaims
+
+// ClaimsMapper defines a function to map token claims to user.
+type ClaimsMapper func(claims Claims) (web.User, error)
+
+// JWT implements bearer token authentication with JSON Web Tokens.
+type JWT struct {
+	// Keys provides keys for verifying signatures.
+	// Required.
+	Keys KeySource
+
+	// Algorithms is a list of accepted signing algorithms.
+	// Optional. Default value []string{"HS256", "RS256", "ES256"}.
+	Algorithms []string
+
+	// Issuer is the expected `iss` claim, it is not checked if empty.
+	Issuer string
+
+	// Audience is the expected `aud` claim, it is not checked if empty.
+	Audience string
+
+	// Leeway is the tolerance of clock skew when checking `exp`, `nbf` and `iat` claims.
+	// Optional. Default value 0.
+	Leeway time.Duration
+
+	// Mapper maps claims to user.
+	// Optional. Default value DefaultClaimsMapper.
+	Mapper ClaimsMapper
+
+	once   sync.Once
+	parser *jwt.Parser
+}
+
+// NewJWT returns a JWT authenticate filter.
+func NewJWT(keys KeySource) *JWT {
+	return &JWT{Keys: keys}
+}
+
+// DefaultClaimsMapper creates user with `sub` and `name` claims.
+func DefaultClaimsMapper(claims Claims) (web.User, error) {
+	id, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, errors.New("jwt: missing subject")
+	}
+
+	name, _ := claims["name"].(string)
+	if name == "" {
+		name = id
+	}
+	return security.NewUser(id, name), nil
+}
+
+// Apply implements `web.Filter` interface.
+func (j *JWT) Apply(next web.HandlerFunc) web.HandlerFunc {
+	if j.Keys == nil {
+		panic("jwt-auth requires a key source")
+	}
+	j.once.Do(j.init)
+
+	return func(ctx web.Context) error {
+		token := bearerToken(ctx)
+		if token == "" {
+			// leave anonymous requests to Authorizer
+			return next(ctx)
+		}
+
+		user, err := j.authenticate(token)
+		if err != nil {
+			ctx.Response().Header().Set(web.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return web.NewError(http.StatusUnauthorized, err.Error())
+		}
+		ctx.SetUser(user)
+		return next(ctx)
+	}
+}
+
+func (j *JWT) init() {
+	if len(j.Algorithms) == 0 {
+		j.Algorithms = []string{"HS256", "RS256", "ES256"}
+	}
+	if j.Mapper == nil {
+		j.Mapper = DefaultClaimsMapper
+	}
+	j.parser = newParser(j.Algorithms, j.Issuer, j.Audience, j.Leeway)
+}
+
+func (j *JWT) authenticate(token string) (web.User, error) {
+	claims, err := parseToken(j.parser, j.Keys, token)
+	if err != nil {
+		return nil, err
+	}
+	if typ, _ := claims["typ"].(string); typ == refreshType {
+		return nil, errors.New("jwt: refresh token can't be used for authentication")
+	}
+	return j.Mapper(claims)
+}
+
+// Issuer mints access and refresh tokens for authenticated users.
+type Issuer struct {
+	// Algorithm is the signing algorithm, e.g. "HS256", "RS256", "ES256".
+	// Optional. Default value "HS256".
+	Algorithm string
+
+	// Key is the signing key, it must be a []byte for HMAC, a *rsa.PrivateKey for RSA or a *ecdsa.PrivateKey for ECDSA.
+	// Required.
+	Key interface{}
+
+	// KeyID is set to `kid` header of tokens, so verifiers can choose key from a key set.
+	KeyID string
+
+	// Issuer is set to `iss` claim of tokens.
+	Issuer string
+
+	// Audience is set to `aud` claim of tokens.
+	Audience string
+
+	// TTL is the lifetime of access tokens.
+	// Optional. Default value 1 hour.
+	TTL time.Duration
+
+	// RefreshTTL is the lifetime of refresh tokens, refresh tokens are not issued if it is negative.
+	// Optional. Default value 7 days.
+	RefreshTTL time.Duration
+
+	// Claims returns extra claims of access tokens for user.
+	// Optional.
+	Claims func(user web.User) Claims
+
+	// Mapper maps claims of refresh token to user, it can be used to reload user or reject disabled users.
+	// Optional. Default value DefaultClaimsMapper.
+	Mapper ClaimsMapper
+}
+
+// Token is the result of issuing, its JSON format follows OAuth 2.0 token response.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+const refreshType = "refresh"
+
+// NewIssuer creates an Issuer with signing algorithm and key.
+func NewIssuer(alg string, key interface{}) *Issuer {
+	return &Issuer{Algorithm: alg, Key: key}
+}
+
+// Keys returns a KeySource for verifying tokens issued by i.
+func (i *Issuer) Keys() KeySource {
+	return StaticKey(i.Key)
+}
+
+// Issue creates tokens for user.
+func (i *Issuer) Issue(user web.User) (*Token, error) {
+	ttl, refreshTTL := i.ttl()
+	now := time.Now()
+
+	claims := Claims{}
+	if i.Claims != nil {
+		for k, v := range i.Claims(user) {
+			claims[k] = v
+		}
+	}
+	i.fill(claims, user, now, ttl)
+	access, err := i.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Token{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl / time.Second),
+	}
+	if refreshTTL > 0 {
+		claims = Claims{"typ": refreshType}
+		i.fill(claims, user, now, refreshTTL)
+		if t.RefreshToken, err = i.sign(claims); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Renew validates refresh token and issues new tokens.
+func (i *Issuer) Renew(refreshToken string) (*Token, error) {
+	alg := i.Algorithm
+	if alg == "" {
+		alg = "HS256"
+	}
+	parser := newParser([]string{alg}, i.Issuer, i.Audience, 0)
+	claims, err := parseToken(parser, i.Keys(), refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if typ, _ := claims["typ"].(string); typ != refreshType {
+		return nil, errors.New("jwt: not a refresh token")
+	}
+
+	mapper := i.Mapper
+	if mapper == nil {
+		mapper = DefaultClaimsMapper
+	}
+	user, err := mapper(claims)
+	if err != nil {
+		return nil, err
+	}
+	return i.Issue(user)
+}
+
+// Login returns a handler which authenticates `name` and `password` fields of request with auth and responds tokens.
+func (i *Issuer) Login(auth *certify.Authenticator) web.HandlerFunc {
+	return func(ctx web.Context) error {
+		m := &struct {
+			Name     string `json:"name"`
+			Password string `json:"password"`
+		}{}
+		if err := ctx.Bind(m); err != nil {
+			return web.NewError(http.StatusBadRequest, err.Error())
+		}
+
+		_, user, err := auth.Login(certify.NewSimpleToken(m.Name, m.Password))
+		if err != nil {
+			return web.NewError(http.StatusUnauthorized, err.Error())
+		}
+
+		t, err := i.Issue(user)
+		if err != nil {
+			return err
+		}
+		return ctx.JSON(t)
+	}
+}
+
+// Refresh is a handler which exchanges `refresh_token` field of request for new tokens.
+func (i *Issuer) Refresh(ctx web.Context) error {
+	m := &struct {
+		RefreshToken string `json:"refresh_token" bind:"refresh_token"`
+	}{}
+	if err := ctx.Bind(m); err != nil {
+		return web.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	t, err := i.Renew(m.RefreshToken)
+	if err != nil {
+		return web.NewError(http.StatusUnauthorized, err.Error())
+	}
+	return ctx.JSON(t)
+}
+
+func (i *Issuer) ttl() (ttl, refreshTTL time.Duration) {
+	ttl, refreshTTL = i.TTL, i.RefreshTTL
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	if refreshTTL == 0 {
+		refreshTTL = 7 * 24 * time.Hour
+	}
+	return
+}
+
+func (i *Issuer) fill(claims Claims, user web.User, now time.Time, ttl time.Duration) {
+	claims["sub"] = user.ID()
+	claims["name"] = user.Name()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	if i.Issuer != "" {
+		claims["iss"] = i.Issuer
+	}
+	if i.Audience != "" {
+		claims["aud"] = i.Audience
+	}
+}
+
+func (i *Issuer) sign(claims Claims) (string, error) {
+	alg := i.Algorithm
+	if alg == "" {
+		alg = "HS256"
+	}
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return "", errors.Format("jwt: unsupported algorithm '%s'", alg)
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if i.KeyID != "" {
+		token.Header["kid"] = i.KeyID
+	}
+	key := i.Key
+	if s, ok := key.(string); ok {
+		key = []byte(s)
+	}
+	return token.SignedString(key)
+}
+
+func newParser(algorithms []string, issuer, audience string, leeway time.Duration) *jwt.Parser {
+	opts := []jwt.ParserOption{jwt.WithValidMethods(algorithms), jwt.WithExpirationRequired()}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+	if leeway > 0 {
+		opts = append(opts, jwt.WithLeeway(leeway))
+	}
+	return jwt.NewParser(opts...)
+}
+
+func parseToken(parser *jwt.Parser, keys KeySource, token string) (Claims, error) {
+	claims := Claims{}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := keys.Key(kid, t.Method.Alg())
+		if err != nil {
+			return nil, err
+		}
+		if !matchKey(key, t.Method.Alg()) {
+			return nil, errors.Format("jwt: key doesn't match algorithm '%s'", t.Method.Alg())
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func bearerToken(ctx web.Context) string {
+	const prefix = "Bearer "
+
+	auth := ctx.Header(web.HeaderAuthorization)
+	if len(auth) > len(prefix) && strings.EqualFold(auth[:len(prefix)], prefix) {
+		return strings.TrimSpace(auth[len(prefix):])
+	}
+	return ""
+}
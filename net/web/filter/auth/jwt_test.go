@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/security"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	issuers := []*Issuer{
+		NewIssuer("HS256", []byte("secret")),
+		NewIssuer("RS256", rsaKey),
+		NewIssuer("ES256", ecKey),
+	}
+	for _, issuer := range issuers {
+		issuer.Issuer = "auxo"
+		s := newServer(&JWT{Keys: issuer.Keys(), Issuer: "auxo"})
+
+		token, err := issuer.Issue(security.NewUser("1", "admin"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, get(s, token.AccessToken).Code)
+		assert.Equal(t, "1:admin", get(s, token.AccessToken).Body.String())
+		assert.Equal(t, "anonymous", get(s, "").Body.String())
+		assert.Equal(t, http.StatusUnauthorized, get(s, token.AccessToken+"x").Code)
+		assert.Equal(t, http.StatusUnauthorized, get(s, token.RefreshToken).Code)
+
+		renewed, err := issuer.Renew(token.RefreshToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "1:admin", get(s, renewed.AccessToken).Body.String())
+		_, err = issuer.Renew(token.AccessToken)
+		assert.Error(t, err)
+	}
+}
+
+func TestJWT_Leeway(t *testing.T) {
+	issuer := NewIssuer("HS256", "secret")
+	issuer.TTL = time.Nanosecond
+	token, err := issuer.Issue(security.NewUser("1", "admin"))
+	assert.NoError(t, err)
+	time.Sleep(time.Second)
+
+	s := newServer(&JWT{Keys: issuer.Keys()})
+	assert.Equal(t, http.StatusUnauthorized, get(s, token.AccessToken).Code)
+
+	s = newServer(&JWT{Keys: issuer.Keys(), Leeway: time.Minute})
+	assert.Equal(t, http.StatusOK, get(s, token.AccessToken).Code)
+}
+
+func TestJWKS(t *testing.T) {
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, map[string]interface{}{"k1": &key1.PublicKey})
+
+	jwks := NewJWKS(path)
+	jwks.MinRefresh = time.Millisecond
+	s := newServer(&JWT{Keys: jwks})
+
+	i1 := &Issuer{Algorithm: "RS256", Key: key1, KeyID: "k1"}
+	t1, err := i1.Issue(security.NewUser("1", "admin"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, get(s, t1.AccessToken).Code)
+
+	// rotate keys
+	writeJWKS(t, path, map[string]interface{}{"k2": &key2.PublicKey})
+	time.Sleep(2 * time.Millisecond)
+
+	i2 := &Issuer{Algorithm: "ES256", Key: key2, KeyID: "k2"}
+	t2, err := i2.Issue(security.NewUser("2", "guest"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2:guest", get(s, t2.AccessToken).Body.String())
+	assert.Equal(t, http.StatusUnauthorized, get(s, t1.AccessToken).Code)
+}
+
+func TestJWKS_SlowRefresh(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, map[string]interface{}{"k1": &key.PublicKey})
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	block := make(chan struct{})
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			<-block
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	jwks := NewJWKS(srv.URL)
+	jwks.Refresh = time.Millisecond
+	_, err = jwks.Key("k1", "RS256")
+	assert.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+
+	// lookups use current keys while the key server hangs
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		_, err = jwks.Key("k1", "RS256")
+		assert.NoError(t, err)
+	}
+	assert.True(t, time.Since(start) < time.Second)
+
+	// only one refresh is in flight
+	for i := 0; i < 100 && atomic.LoadInt32(&calls) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func newServer(f web.Filter) *web.Server {
+	s := web.Default()
+	s.Use(f)
+	s.Get("/", func(ctx web.Context) error {
+		if u := ctx.User(); u != nil && !u.Anonymous() {
+			return ctx.Text(u.ID() + ":" + u.Name())
+		}
+		return ctx.Text("anonymous")
+	})
+	return s
+}
+
+func get(s *web.Server, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set(web.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func writeJWKS(t *testing.T, path string, keys map[string]interface{}) {
+	encode := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+
+	// unsupported keys must be ignored
+	set := []map[string]string{{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set = append(set, map[string]string{"kty": "RSA", "kid": kid, "n": encode(k.N), "e": encode(big.NewInt(int64(k.E)))})
+		case *ecdsa.PublicKey:
+			set = append(set, map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": encode(k.X), "y": encode(k.Y)})
+		}
+	}
+	b, err := json.Marshal(map[string]interface{}{"keys": set})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, b, 0600))
+}
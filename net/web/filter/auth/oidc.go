@@ -0,0 +1,501 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cuigh/auxo/config"
+	"github.com/cuigh/auxo/errors"
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/security"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDC implements single sign-on with OpenID Connect, it performs the authorization code flow with PKCE.
+// Authenticated sessions are kept in the cookie of Form, so Form must be applied as a filter:
+//
+//	o, err := auth.LoadOIDC("web.oidc")
+//	form := auth.NewForm(o.Identify)
+//	o.Form = form
+//	s.Use(form)
+//	s.Get("/login", o.Login, web.WithAuthorize("*"))
+//	s.Get("/login/callback", o.Callback, web.WithAuthorize("*"))
+type OIDC struct {
+	// Issuer is the URL of provider, its discovery document is loaded from `{Issuer}/.well-known/openid-configuration`.
+	// Required.
+	Issuer string
+
+	// ClientID is the client identifier registered at provider, it is also the expected `aud` of ID tokens.
+	// Required.
+	ClientID string
+
+	// ClientSecret is the client secret, it is sent with HTTP basic authentication. Public clients can leave it empty.
+	ClientSecret string
+
+	// RedirectURL is the absolute URL of Callback handler registered at provider.
+	// Required.
+	RedirectURL string
+
+	// Scopes is the list of requested scopes.
+	// Optional. Default value []string{"openid", "profile", "email"}.
+	Scopes []string
+
+	// Leeway is the tolerance of clock skew when checking ID tokens.
+	// Optional. Default value 0.
+	Leeway time.Duration
+
+	// CookieName is the name of cookie which keeps state, nonce and PKCE verifier during sign-in.
+	// Optional. Default value "_oidc".
+	CookieName string
+
+	// Form keeps authenticated sessions.
+	// Required.
+	Form *Form
+
+	// Mapper maps claims of ID token to user.
+	// Optional. Default value OIDCClaimsMapper.
+	Mapper ClaimsMapper
+
+	// Ticket creates the ticket of Form cookie, it must be identifiable by Form.Identifier.
+	// Optional. Default value returns a compact token signed with TicketKey, which only keeps ID and name
+	// of user and can be identified by Identify. Set it if users need more than that.
+	Ticket func(user web.User, claims Claims, idToken string) (string, error)
+
+	// TicketKey is the HMAC key of default tickets.
+	// Optional. Default value a random key, so sessions are lost on restart and can't be shared between instances.
+	TicketKey string
+
+	// TicketTTL is the lifetime of default tickets, it is independent of the expiration of ID tokens.
+	// Optional. Default value Form.Timeout, or 24 hours if it is zero.
+	TicketTTL time.Duration
+
+	// Client is used to call provider endpoints.
+	// Optional. Default value http.Client with 10 seconds timeout.
+	Client *http.Client
+
+	locker   sync.Mutex
+	metadata atomic.Value // *oidcMetadata
+	once     sync.Once
+	tickets  *Issuer
+}
+
+// oidcMetadata is derived from discovery document, it is immutable once loaded.
+type oidcMetadata struct {
+	discovery *oidcDiscovery
+	keys      *JWKS
+	parser    *jwt.Parser
+}
+
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	EndSessionEndpoint    string   `json:"end_session_endpoint"`
+	Algorithms            []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// oidcState is kept in a short-lived cookie between Login and Callback.
+type oidcState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	From     string `json:"f,omitempty"`
+}
+
+// NewOIDC creates an OIDC instance.
+func NewOIDC(issuer, clientID, clientSecret, redirectURL string) *OIDC {
+	return &OIDC{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+	}
+}
+
+// LoadOIDC creates an OIDC instance with provider options loaded from config, like:
+//
+//	web:
+//	  oidc:
+//	    issuer: https://sso.example.com
+//	    client_id: tools
+//	    client_secret: secret
+//	    redirect_url: https://tools.example.com/login/callback
+//	    scopes: [openid, profile, email]
+//	    ticket_key: key-shared-by-instances
+func LoadOIDC(key string) (*OIDC, error) {
+	o := &OIDC{}
+	if err := config.UnmarshalOption(key, o); err != nil {
+		return nil, err
+	}
+	if o.Issuer == "" || o.ClientID == "" || o.RedirectURL == "" {
+		return nil, errors.Format("oidc: issuer, client_id and redirect_url of '%s' are required", key)
+	}
+	return o, nil
+}
+
+// OIDCClaimsMapper creates user with `sub` claim and the first non-empty claim of
+// `name`, `preferred_username` and `email` as name.
+func OIDCClaimsMapper(claims Claims) (web.User, error) {
+	id, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, errors.New("oidc: missing subject")
+	}
+
+	name := id
+	for _, c := range []string{"name", "preferred_username", "email"} {
+		if s, _ := claims[c].(string); s != "" {
+			name = s
+			break
+		}
+	}
+	return security.NewUser(id, name), nil
+}
+
+// Login is a handler which redirects user to the authorization endpoint of provider,
+// `from` query is kept and user is redirected back to it after signing in.
+func (o *OIDC) Login(ctx web.Context) error {
+	d, err := o.discover()
+	if err != nil {
+		return web.NewError(http.StatusBadGateway, err.Error())
+	}
+
+	st := &oidcState{State: randomString(), Nonce: randomString(), Verifier: randomString(), From: ctx.Q("from")}
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	ctx.SetCookie(&http.Cookie{
+		Name:     o.cookieName(),
+		Value:    base64.RawURLEncoding.EncodeToString(b),
+		Path:     o.cookiePath(),
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(o.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	challenge := sha256.Sum256([]byte(st.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.ClientID},
+		"redirect_uri":          {o.RedirectURL},
+		"scope":                 {strings.Join(o.scopes(), " ")},
+		"state":                 {st.State},
+		"nonce":                 {st.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	return ctx.Redirect(appendQuery(d.AuthorizationEndpoint, q))
+}
+
+// Callback is a handler which completes sign-in, it exchanges authorization code for ID token,
+// validates the token and saves the session into cookie of Form. Like Form.Login, ID of current
+// session is regenerated to prevent session fixation.
+func (o *OIDC) Callback(ctx web.Context) error {
+	if o.Form == nil {
+		panic("oidc-auth requires a Form")
+	}
+
+	if e := ctx.Q("error"); e != "" {
+		return web.NewError(http.StatusUnauthorized, "oidc: "+e+" "+ctx.Q("error_description"))
+	}
+
+	st := o.state(ctx)
+	ctx.SetCookie(&http.Cookie{Name: o.cookieName(), Path: o.cookiePath(), MaxAge: -1})
+	if st == nil || st.State == "" || st.State != ctx.Q("state") {
+		return web.NewError(http.StatusBadRequest, "oidc: invalid state")
+	}
+
+	idToken, err := o.exchange(ctx.Q("code"), st.Verifier)
+	if err != nil {
+		return web.NewError(http.StatusUnauthorized, err.Error())
+	}
+
+	claims, err := o.verify(idToken)
+	if err != nil {
+		return web.NewError(http.StatusUnauthorized, err.Error())
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != st.Nonce {
+		return web.NewError(http.StatusUnauthorized, "oidc: invalid nonce")
+	}
+
+	user, err := o.mapper()(claims)
+	if err != nil {
+		return web.NewError(http.StatusUnauthorized, err.Error())
+	}
+
+	var ticket string
+	if o.Ticket == nil {
+		ticket, err = o.issueTicket(user)
+	} else {
+		ticket, err = o.Ticket(user, claims, idToken)
+	}
+	if err != nil {
+		return err
+	}
+	regenerateSession(ctx)
+	o.Form.renewTicket(ctx, ticket)
+
+	from := st.From
+	if !isLocalURL(from) {
+		// only local paths are allowed to avoid open redirection
+		from = o.Form.DefaultURL
+	}
+	return ctx.Redirect(from)
+}
+
+// Logout is a handler which clears session and redirects user to the end session endpoint of provider if it exists.
+func (o *OIDC) Logout(ctx web.Context) error {
+	ctx.SetCookie(&http.Cookie{Name: o.Form.CookieName, Path: o.Form.CookiePath, MaxAge: -1})
+	if d, err := o.discover(); err == nil && d.EndSessionEndpoint != "" {
+		return ctx.Redirect(appendQuery(d.EndSessionEndpoint, url.Values{"client_id": {o.ClientID}}))
+	}
+	return ctx.Redirect(o.Form.DefaultURL)
+}
+
+// Identify validates default ticket and returns the user with ID and name, it can be used as
+// Form.Identifier if Ticket is not set. Nil is returned if the ticket is invalid or expired.
+func (o *OIDC) Identify(ticket string) web.User {
+	i := o.ticketIssuer()
+	parser := newParser([]string{i.Algorithm}, i.Issuer, i.Audience, 0)
+	claims, err := parseToken(parser, i.Keys(), ticket)
+	if err != nil {
+		return nil
+	}
+	user, err := DefaultClaimsMapper(claims)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+func (o *OIDC) issueTicket(user web.User) (string, error) {
+	t, err := o.ticketIssuer().Issue(user)
+	if err != nil {
+		return "", err
+	}
+	return t.AccessToken, nil
+}
+
+// ticketIssuer returns the issuer of default tickets, it is created on first use.
+func (o *OIDC) ticketIssuer() *Issuer {
+	o.once.Do(func() {
+		key := o.TicketKey
+		if key == "" {
+			key = randomString()
+		}
+		ttl := o.TicketTTL
+		if ttl <= 0 && o.Form != nil {
+			ttl = o.Form.Timeout
+		}
+		if ttl <= 0 {
+			ttl = 24 * time.Hour
+		}
+		o.tickets = &Issuer{Algorithm: "HS256", Key: []byte(key), Audience: o.ClientID, TTL: ttl, RefreshTTL: -1}
+	})
+	return o.tickets
+}
+
+// discover loads discovery document of provider, it is retried on next call if failed.
+func (o *OIDC) discover() (*oidcDiscovery, error) {
+	m, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	return m.discovery, nil
+}
+
+// load returns the cached metadata without locking, the lock is only held while loading it.
+func (o *OIDC) load() (*oidcMetadata, error) {
+	if m, ok := o.metadata.Load().(*oidcMetadata); ok {
+		return m, nil
+	}
+
+	o.locker.Lock()
+	defer o.locker.Unlock()
+
+	if m, ok := o.metadata.Load().(*oidcMetadata); ok {
+		return m, nil
+	}
+
+	issuer := strings.TrimSuffix(o.Issuer, "/")
+	resp, err := o.client().Get(issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Format("oidc: failed to load discovery document: %s", resp.Status)
+	}
+	d := &oidcDiscovery{}
+	if err = json.NewDecoder(resp.Body).Decode(d); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, errors.Format("oidc: issuer mismatch, expected '%s' but got '%s'", issuer, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document misses required endpoints")
+	}
+
+	algorithms := d.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"RS256"}
+	}
+	m := &oidcMetadata{
+		discovery: d,
+		keys:      &JWKS{Location: d.JWKSURI, Client: o.client()},
+		parser:    newParser(algorithms, d.Issuer, o.ClientID, o.Leeway),
+	}
+	o.metadata.Store(m)
+	return m, nil
+}
+
+func (o *OIDC) exchange(code, verifier string) (string, error) {
+	if code == "" {
+		return "", errors.New("oidc: missing authorization code")
+	}
+
+	d, err := o.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if o.ClientSecret == "" {
+		form.Set("client_id", o.ClientID)
+	}
+	req, err := http.NewRequest(http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(web.HeaderContentType, web.MIMEApplicationForm)
+	req.Header.Set(web.HeaderAccept, web.MIMEApplicationJSON)
+	if o.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	result := &struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil && resp.StatusCode == http.StatusOK {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		return "", errors.Format("oidc: failed to exchange code: %s %s %s", resp.Status, result.Error, result.ErrorDescription)
+	}
+	if result.IDToken == "" {
+		return "", errors.New("oidc: missing id_token")
+	}
+	return result.IDToken, nil
+}
+
+func (o *OIDC) verify(idToken string) (Claims, error) {
+	m, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	return parseToken(m.parser, m.keys, idToken)
+}
+
+func (o *OIDC) state(ctx web.Context) *oidcState {
+	cookie, err := ctx.Cookie(o.cookieName())
+	if err != nil || cookie == nil {
+		return nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	st := &oidcState{}
+	if json.Unmarshal(b, st) != nil {
+		return nil
+	}
+	return st
+}
+
+func (o *OIDC) mapper() ClaimsMapper {
+	if o.Mapper == nil {
+		return OIDCClaimsMapper
+	}
+	return o.Mapper
+}
+
+func (o *OIDC) scopes() []string {
+	if len(o.Scopes) == 0 {
+		return []string{"openid", "profile", "email"}
+	}
+	return o.Scopes
+}
+
+func (o *OIDC) cookieName() string {
+	if o.CookieName == "" {
+		return "_oidc"
+	}
+	return o.CookieName
+}
+
+func (o *OIDC) cookiePath() string {
+	if o.Form != nil && o.Form.CookiePath != "" {
+		return o.Form.CookiePath
+	}
+	return "/"
+}
+
+func (o *OIDC) client() *http.Client {
+	if o.Client == nil {
+		return &http.Client{Timeout: 10 * time.Second}
+	}
+	return o.Client
+}
+
+func appendQuery(endpoint string, q url.Values) string {
+	if strings.Contains(endpoint, "?") {
+		return endpoint + "&" + q.Encode()
+	}
+	return endpoint + "?" + q.Encode()
+}
+
+// isLocalURL reports whether u is a path of current site, like `/profile?tab=1`.
+func isLocalURL(u string) bool {
+	// browsers treat `\` as `/`, so `/\evil.com` would be a protocol-relative URL
+	if u == "" || u[0] != '/' || strings.ContainsRune(u, '\\') {
+		return false
+	}
+	r, err := url.Parse(u)
+	return err == nil && r.Scheme == "" && r.Host == ""
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
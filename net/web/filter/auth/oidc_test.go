@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/security"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestOIDC(t *testing.T) {
+	p := newProvider(t)
+	o, s := newOIDCServer(p.URL)
+	sess := &stubSession{}
+	s.UseFunc(withSession(sess))
+
+	// sign in
+	rec := request(s, "/login?from=/profile", nil)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	cookies := rec.Result().Cookies()
+
+	callback := p.authorize(t, rec.Header().Get(web.HeaderLocation))
+	rec = request(s, callback, cookies)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/profile", rec.Header().Get(web.HeaderLocation))
+	assert.Equal(t, 1, sess.regenerated)
+
+	session := findCookie(rec.Result().Cookies(), o.Form.CookieName)
+	assert.NotNil(t, session)
+	assert.Equal(t, "1:Alice", request(s, "/", []*http.Cookie{session}).Body.String())
+	assert.Equal(t, "anonymous", request(s, "/", []*http.Cookie{{Name: "_u", Value: session.Value + "x"}}).Body.String())
+	// the ticket is signed by client instead of provider, it is not identifiable by other clients
+	other, _ := newOIDCServer(p.URL)
+	assert.Nil(t, other.Identify(session.Value))
+	idToken, err := p.issuer.Issue(security.NewUser("1", "Alice"))
+	assert.NoError(t, err)
+	assert.Nil(t, o.Identify(idToken.AccessToken))
+
+	// replaying the callback with the state cookie passes the state check, but the code is single-use at provider
+	rec = request(s, callback, cookies)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid_grant")
+	// without the cookie, state can't be verified
+	assert.Equal(t, http.StatusBadRequest, request(s, callback, nil).Code)
+}
+
+func TestOIDC_Invalid(t *testing.T) {
+	p := newProvider(t)
+	_, s := newOIDCServer(p.URL)
+
+	// state mismatch
+	rec := request(s, "/login", nil)
+	cookies := rec.Result().Cookies()
+	callback := p.authorize(t, rec.Header().Get(web.HeaderLocation))
+	u, _ := url.Parse(callback)
+	q := u.Query()
+	q.Set("state", "forged")
+	u.RawQuery = q.Encode()
+	assert.Equal(t, http.StatusBadRequest, request(s, u.String(), cookies).Code)
+
+	// PKCE verifier mismatch
+	rec = request(s, "/login", nil)
+	callback = p.authorize(t, rec.Header().Get(web.HeaderLocation))
+	st := &oidcState{}
+	b, _ := base64.RawURLEncoding.DecodeString(rec.Result().Cookies()[0].Value)
+	assert.NoError(t, json.Unmarshal(b, st))
+	st.Verifier = "wrong"
+	b, _ = json.Marshal(st)
+	cookie := &http.Cookie{Name: "_oidc", Value: base64.RawURLEncoding.EncodeToString(b)}
+	assert.Equal(t, http.StatusUnauthorized, request(s, callback, []*http.Cookie{cookie}).Code)
+
+	// provider error
+	assert.Equal(t, http.StatusUnauthorized, request(s, "/callback?error=access_denied", nil).Code)
+}
+
+func TestOIDC_Redirect(t *testing.T) {
+	p := newProvider(t)
+	_, s := newOIDCServer(p.URL)
+
+	cases := map[string]string{
+		"/profile?tab=1":      "/profile?tab=1",
+		"":                    "/",
+		"profile":             "/",
+		"//evil.com":          "/",
+		"/\\evil.com":         "/",
+		"/\\/evil.com":        "/",
+		"https://evil.com":    "/",
+		"/\tevil.com":         "/",
+		"javascript:alert(1)": "/",
+	}
+	for from, expected := range cases {
+		rec := request(s, "/login?"+url.Values{"from": {from}}.Encode(), nil)
+		callback := p.authorize(t, rec.Header().Get(web.HeaderLocation))
+		rec = request(s, callback, rec.Result().Cookies())
+		assert.Equal(t, http.StatusFound, rec.Code, from)
+		assert.Equal(t, expected, rec.Header().Get(web.HeaderLocation), from)
+	}
+}
+
+func newOIDCServer(issuer string) (*OIDC, *web.Server) {
+	o := NewOIDC(issuer, "tools", "secret", "http://tools.test/callback")
+	o.Form = NewForm(o.Identify)
+
+	s := newServer(o.Form)
+	s.Get("/login", o.Login)
+	s.Get("/callback", o.Callback)
+	return o, s
+}
+
+// provider is a minimal OpenID Connect provider for tests, it signs in user `1` automatically.
+type provider struct {
+	*httptest.Server
+	issuer *Issuer
+
+	locker sync.Mutex
+	codes  map[string]url.Values // code -> authorize request
+}
+
+func newProvider(t *testing.T) *provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	p := &provider{codes: make(map[string]url.Values)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := func(i *big.Int) string {
+			return base64.RawURLEncoding.EncodeToString(i.Bytes())
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "k1", "use": "sig", "n": encode(key.N), "e": encode(big.NewInt(int64(key.E)))},
+		}})
+	})
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	p.issuer = &Issuer{Algorithm: "RS256", Key: key, KeyID: "k1", Issuer: p.URL, Audience: "tools", RefreshTTL: -1}
+	return p
+}
+
+// authorize simulates the authorization endpoint, it returns the callback URL with code and state.
+func (p *provider) authorize(t *testing.T, location string) string {
+	u, err := url.Parse(location)
+	assert.NoError(t, err)
+	assert.Equal(t, p.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+
+	q := u.Query()
+	assert.Equal(t, "code", q.Get("response_type"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+
+	code := randomString()
+	p.locker.Lock()
+	p.codes[code] = q
+	p.locker.Unlock()
+
+	callback, err := url.Parse(q.Get("redirect_uri"))
+	assert.NoError(t, err)
+	return callback.Path + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+}
+
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	fail := func(e string) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": e})
+	}
+
+	if id, secret, _ := r.BasicAuth(); id != "tools" || secret != "secret" {
+		fail("invalid_client")
+		return
+	}
+
+	code := r.PostFormValue("code")
+	p.locker.Lock()
+	q := p.codes[code]
+	delete(p.codes, code)
+	p.locker.Unlock()
+	if q == nil || r.PostFormValue("redirect_uri") != q.Get("redirect_uri") {
+		fail("invalid_grant")
+		return
+	}
+
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != q.Get("code_challenge") {
+		fail("invalid_grant")
+		return
+	}
+
+	issuer := *p.issuer
+	issuer.Claims = func(user web.User) Claims {
+		return Claims{"nonce": q.Get("nonce"), "email": "alice@example.com"}
+	}
+	token, err := issuer.Issue(security.NewUser("1", "Alice"))
+	if err != nil {
+		fail("server_error")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"id_token": token.AccessToken, "token_type": "Bearer"})
+}
@@ -5,7 +5,9 @@ import (
 	"net/url"
 
 	"github.com/cuigh/auxo/data"
+	"github.com/cuigh/auxo/log"
 	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/security/rbac"
 )
 
 type Authorizer struct {
@@ -21,6 +23,20 @@ func NewAuthorizer(checker func(user web.User, handler web.HandlerInfo) bool) *A
 	}
 }
 
+// NewPolicyAuthorizer creates an Authorizer which checks authorize rules of handlers with the policy engine,
+// like `auth:"perm:order.write"` or `auth:"role:admin"`. Handlers without a rule are checked with
+// `rbac.Options.DefaultRule`, and denied if it is empty. Denied requests are logged with the decision.
+func NewPolicyAuthorizer(e *rbac.Engine) *Authorizer {
+	logger := log.Get(PkgName)
+	return NewAuthorizer(func(user web.User, handler web.HandlerInfo) bool {
+		d := e.Explain(user, handler.Authorize())
+		if !d.Allowed {
+			logger.Debugf("authorizer > %s for user %s on %s", d, user.Name(), handler.Name())
+		}
+		return d.Allowed
+	})
+}
+
 // Apply implements `web.Filter` interface.
 func (a *Authorizer) Apply(next web.HandlerFunc) web.HandlerFunc {
 	if a.Checker == nil {
@@ -0,0 +1,49 @@
+package filter
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/net/web/webtest"
+	"github.com/cuigh/auxo/security"
+	"github.com/cuigh/auxo/security/rbac"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+type roleUser struct {
+	security.User
+	roles []string
+}
+
+func (u *roleUser) Roles() []string {
+	return u.roles
+}
+
+func TestPolicyAuthorizer(t *testing.T) {
+	e, err := rbac.New(&rbac.Policy{Roles: []rbac.Role{
+		{Name: "editor", Permissions: []string{"order.*"}},
+	}})
+	assert.NoError(t, err)
+
+	s := web.Default()
+	s.Use(NewPolicyAuthorizer(e))
+	s.Post("/orders", func(ctx web.Context) error { return ctx.Text("ok") }, web.WithAuthorize("perm:order.write"))
+	s.Delete("/orders", func(ctx web.Context) error { return ctx.Text("ok") }, web.WithAuthorize("role:admin"))
+
+	cases := []struct {
+		Method string
+		Roles  []string
+		Status int
+	}{
+		{http.MethodPost, []string{"editor"}, http.StatusOK},
+		{http.MethodPost, nil, http.StatusForbidden},
+		{http.MethodDelete, []string{"editor"}, http.StatusForbidden},
+		{http.MethodDelete, []string{"admin"}, http.StatusOK},
+	}
+	client := webtest.New(t, s)
+	for _, c := range cases {
+		u := &roleUser{User: security.NewUser("1", "test"), roles: c.Roles}
+		client.As(u).Request(c.Method, "/orders").Do().Status(c.Status)
+	}
+}
@@ -0,0 +1,289 @@
+package filter
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/cuigh/auxo/net/web"
+)
+
+// Compression encodings
+const (
+	EncodingBrotli  = "br"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compress is a filter which compresses response body according to the `Accept-Encoding` header.
+type Compress struct {
+	// Encodings is a list of supported encodings in order of preference.
+	// Optional. Default value []string{"br", "gzip", "deflate"}.
+	Encodings []string
+
+	// Level is the compression level, valid range depends on the encoding.
+	// Optional. Default value is the default level of each encoding.
+	Level int
+
+	// MinLength is the minimum size of body to compress, smaller bodies are sent as is.
+	// Optional. Default value 1024.
+	MinLength int
+
+	// SkipTypes is a list of MIME types which are already compressed or streamed, an item ends with '/' matches
+	// all types with the prefix, e.g. "image/".
+	// Optional. Default value DefaultSkipTypes.
+	SkipTypes []string
+
+	once  sync.Once
+	pools map[string]*sync.Pool
+}
+
+// DefaultSkipTypes is the default MIME types of Compress.SkipTypes.
+var DefaultSkipTypes = []string{
+	"image/", "audio/", "video/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-7z-compressed",
+	"application/x-rar-compressed", "application/x-bzip2", "application/x-xz", "application/pdf",
+	"application/octet-stream", "text/event-stream",
+}
+
+// NewCompress returns a Compress instance with default options.
+func NewCompress() *Compress {
+	return &Compress{}
+}
+
+// Apply implements `web.Filter` interface.
+func (c *Compress) Apply(next web.HandlerFunc) web.HandlerFunc {
+	// global filters are applied on every request, so pools must be created only once
+	c.once.Do(c.init)
+
+	return func(ctx web.Context) error {
+		r := ctx.Request()
+		if r.Method == http.MethodHead || r.Header.Get(web.HeaderUpgrade) != "" {
+			return next(ctx)
+		}
+
+		ctx.Response().Header().Add(web.HeaderVary, web.HeaderAcceptEncoding)
+		enc := web.AcceptEncoding(r.Header.Get(web.HeaderAcceptEncoding), c.Encodings...)
+		if enc == "" {
+			return next(ctx)
+		}
+
+		w := &compressWriter{
+			ResponseWriter: ctx.Response().Unwrap(),
+			filter:         c,
+			encoding:       enc,
+			status:         http.StatusOK,
+		}
+		ctx.SetResponse(w)
+		defer func() {
+			w.close()
+			ctx.SetResponse(w.ResponseWriter)
+		}()
+		return next(ctx)
+	}
+}
+
+func (c *Compress) init() {
+	if len(c.Encodings) == 0 {
+		c.Encodings = []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+	}
+	if c.MinLength <= 0 {
+		c.MinLength = 1024
+	}
+	if c.SkipTypes == nil {
+		c.SkipTypes = DefaultSkipTypes
+	}
+	c.pools = make(map[string]*sync.Pool)
+	for _, enc := range c.Encodings {
+		c.pools[enc] = c.newPool(enc)
+	}
+}
+
+func (c *Compress) newPool(enc string) *sync.Pool {
+	var fn func() interface{}
+	switch enc {
+	case EncodingBrotli:
+		level := brotli.DefaultCompression
+		if c.Level != 0 {
+			level = c.Level
+		}
+		fn = func() interface{} { return brotli.NewWriterLevel(nil, level) }
+	case EncodingGzip:
+		level := gzip.DefaultCompression
+		if c.Level != 0 {
+			level = c.Level
+		}
+		fn = func() interface{} {
+			w, err := gzip.NewWriterLevel(nil, level)
+			if err != nil {
+				w = gzip.NewWriter(nil)
+			}
+			return w
+		}
+	case EncodingDeflate:
+		level := flate.DefaultCompression
+		if c.Level != 0 {
+			level = c.Level
+		}
+		fn = func() interface{} {
+			w, err := flate.NewWriter(nil, level)
+			if err != nil {
+				w, _ = flate.NewWriter(nil, flate.DefaultCompression)
+			}
+			return w
+		}
+	default:
+		panic("compress > unsupported encoding: " + enc)
+	}
+	return &sync.Pool{New: fn}
+}
+
+func (c *Compress) skip(ct string) bool {
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	ct = strings.ToLower(strings.TrimSpace(ct))
+	for _, t := range c.SkipTypes {
+		if t == ct || (strings.HasSuffix(t, "/") && strings.HasPrefix(ct, t)) {
+			// svg images are text and compress well
+			return ct != "image/svg+xml"
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the beginning of body until MinLength is reached, then decides whether to compress.
+type compressWriter struct {
+	http.ResponseWriter
+	filter      *Compress
+	encoding    string
+	status      int
+	buf         []byte
+	encoder     encoder
+	wroteHeader bool
+	decided     bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.filter.MinLength {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements the http.Flusher interface, buffered data is compressed and sent to client.
+func (w *compressWriter) Flush() {
+	if w.wroteHeader && !w.decided {
+		_ = w.decide(true)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Push implements the http.Pusher interface.
+func (w *compressWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the original http.ResponseWriter, it is used by http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) decide(compress bool) (err error) {
+	w.decided = true
+
+	h := w.Header()
+	if compress && w.compressible(h) {
+		h.Del(web.HeaderContentLength)
+		h.Set(web.HeaderContentEncoding, w.encoding)
+		w.encoder = w.filter.pools[w.encoding].Get().(encoder)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buf) > 0 {
+		if w.encoder != nil {
+			_, err = w.encoder.Write(w.buf)
+		} else {
+			_, err = w.ResponseWriter.Write(w.buf)
+		}
+		w.buf = nil
+	}
+	return
+}
+
+func (w *compressWriter) compressible(h http.Header) bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified ||
+		w.status == http.StatusPartialContent {
+		return false
+	}
+	if h.Get(web.HeaderContentEncoding) != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+
+	ct := h.Get(web.HeaderContentType)
+	if ct == "" {
+		// detect content type before compressing, otherwise it will be detected from compressed data
+		ct = http.DetectContentType(w.buf)
+		h.Set(web.HeaderContentType, ct)
+	}
+	return !w.filter.skip(ct)
+}
+
+func (w *compressWriter) close() {
+	if w.wroteHeader && !w.decided {
+		_ = w.decide(false)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.encoder.Reset(nil)
+		w.filter.pools[w.encoding].Put(w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("auxo ", 1000)
+
+	s := web.Default()
+	s.Use(NewCompress())
+	s.Get("/large", func(ctx web.Context) error { return ctx.Text(large) })
+	s.Get("/small", func(ctx web.Context) error { return ctx.Text("auxo") })
+	s.Get("/image", func(ctx web.Context) error {
+		return ctx.SetContentType("image/png").Data([]byte(large))
+	})
+	s.Get("/events", func(ctx web.Context) error {
+		return ctx.SetContentType(web.MIMETextEventStream).Data([]byte(large))
+	})
+
+	cases := []struct {
+		Path     string
+		Accept   string
+		Encoding string
+	}{
+		{"/large", "gzip, deflate", "gzip"},
+		{"/large", "br;q=0.5, gzip", "gzip"},
+		{"/large", "br", "br"},
+		{"/large", "", ""},
+		{"/small", "gzip", ""},
+		{"/image", "gzip", ""},
+		{"/events", "gzip", ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.Path, nil)
+		req.Header.Set(web.HeaderAcceptEncoding, c.Accept)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, c.Encoding, rec.Header().Get(web.HeaderContentEncoding))
+		assert.Equal(t, web.HeaderAcceptEncoding, rec.Header().Get(web.HeaderVary))
+		if c.Encoding == "gzip" {
+			r, err := gzip.NewReader(rec.Body)
+			assert.NoError(t, err)
+			b, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, large, string(b))
+			assert.True(t, strings.HasPrefix(rec.Header().Get(web.HeaderContentType), web.MIMETextPlain))
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package filter
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"sync"
+
+	"github.com/cuigh/auxo/net/web"
+)
+
+const csrfLength = 32
+
+// CSRFStore persists the secret CSRF token of a client.
+type CSRFStore interface {
+	// Load returns the saved token, it returns an empty string if not found.
+	Load(ctx web.Context) string
+	// Save saves the token for subsequent requests.
+	Save(ctx web.Context, token string) error
+}
+
+// CSRFCookie stores tokens in cookies, which implements the double-submit cookie pattern.
+type CSRFCookie struct {
+	// Name is the cookie name.
+	// Optional. Default value "_csrf".
+	Name     string
+	Domain   string
+	Path     string // default '/'
+	MaxAge   int
+	Secure   bool
+	HTTPOnly bool // set to false if scripts need to read the token from cookie
+	SameSite http.SameSite
+}
+
+// Load implements CSRFStore interface.
+func (c *CSRFCookie) Load(ctx web.Context) string {
+	if cookie, err := ctx.Cookie(c.name()); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// Save implements CSRFStore interface.
+func (c *CSRFCookie) Save(ctx web.Context, token string) error {
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+	ctx.SetCookie(&http.Cookie{
+		Name:     c.name(),
+		Value:    token,
+		Domain:   c.Domain,
+		Path:     path,
+		MaxAge:   c.MaxAge,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+		SameSite: c.SameSite,
+	})
+	return nil
+}
+
+func (c *CSRFCookie) name() string {
+	if c.Name == "" {
+		return "_csrf"
+	}
+	return c.Name
+}
+
+// CSRF is a filter which protects unsafe requests from Cross-Site Request Forgery.
+// A secret token is kept by Store, and unsafe requests must send it back through header or form field.
+// Handlers can be excluded with `web.WithOption("csrf", "off")`.
+type CSRF struct {
+	// Store keeps secret tokens, use session.CSRFStore() to implement the synchronizer token pattern.
+	// Optional. Default value is a CSRFCookie which implements the double-submit cookie pattern.
+	Store CSRFStore
+
+	// HeaderName is the request header which carries token.
+	// Optional. Default value "X-CSRF-Token".
+	HeaderName string
+
+	// FieldName is the form field which carries token.
+	// Optional. Default value "_csrf".
+	FieldName string
+
+	// Error is returned to ErrorHandler when validation fails.
+	// Optional. Default value is a 403 error.
+	Error error
+
+	once sync.Once
+}
+
+// NewCSRF returns a CSRF filter with default options.
+func NewCSRF() *CSRF {
+	return &CSRF{}
+}
+
+// Apply implements `web.Filter` interface.
+func (c *CSRF) Apply(next web.HandlerFunc) web.HandlerFunc {
+	c.once.Do(c.init)
+
+	return func(ctx web.Context) error {
+		secret := decodeToken(c.Store.Load(ctx))
+		if len(secret) != csrfLength {
+			secret = make([]byte, csrfLength)
+			if _, err := rand.Read(secret); err != nil {
+				return err
+			}
+			if err := c.Store.Save(ctx, base64.RawURLEncoding.EncodeToString(secret)); err != nil {
+				return err
+			}
+		}
+		ctx.Set(web.CSRFKey, &csrfState{filter: c, secret: secret})
+
+		if h := ctx.Handler(); h != nil && h.Option("csrf") == "off" {
+			return next(ctx)
+		}
+
+		switch ctx.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			return next(ctx)
+		}
+
+		token := ctx.Header(c.HeaderName)
+		if token == "" {
+			token = ctx.F(c.FieldName)
+		}
+		if !verifyToken(secret, token) {
+			return c.Error
+		}
+		return next(ctx)
+	}
+}
+
+func (c *CSRF) init() {
+	if c.Store == nil {
+		c.Store = &CSRFCookie{}
+	}
+	if c.HeaderName == "" {
+		c.HeaderName = "X-CSRF-Token"
+	}
+	if c.FieldName == "" {
+		c.FieldName = "_csrf"
+	}
+	if c.Error == nil {
+		c.Error = web.NewError(http.StatusForbidden, "invalid csrf token")
+	}
+}
+
+// csrfState implements web.CSRFTokener.
+type csrfState struct {
+	filter *CSRF
+	secret []byte
+}
+
+// Token returns a token masked with random bytes on every call, so it can't be deduced from compressed responses.
+func (s *csrfState) Token() string {
+	b := make([]byte, csrfLength*2)
+	if _, err := rand.Read(b[:csrfLength]); err != nil {
+		return ""
+	}
+	for i := 0; i < csrfLength; i++ {
+		b[csrfLength+i] = b[i] ^ s.secret[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Field returns a hidden input element with token.
+func (s *csrfState) Field() template.HTML {
+	token := s.Token()
+	if token == "" {
+		return ""
+	}
+	name := s.filter.FieldName
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(name) + `" value="` + token + `">`)
+}
+
+// CSRFToken returns a token for the current request which should be sent back with unsafe requests.
+// The token is masked with random bytes on every call, so it can't be deduced from compressed responses.
+// It returns an empty string if CSRF filter is not applied. It is the same as web.CSRFToken.
+func CSRFToken(ctx web.Context) string {
+	return web.CSRFToken(ctx)
+}
+
+// CSRFField returns a hidden input element with token for HTML forms. It is the same as web.CSRFField.
+func CSRFField(ctx web.Context) template.HTML {
+	return web.CSRFField(ctx)
+}
+
+func decodeToken(s string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// verifyToken checks masked token or raw token which is read from cookie by scripts.
+func verifyToken(secret []byte, token string) bool {
+	b := decodeToken(token)
+	switch len(b) {
+	case csrfLength:
+	case csrfLength * 2:
+		for i := 0; i < csrfLength; i++ {
+			b[csrfLength+i] ^= b[i]
+		}
+		b = b[csrfLength:]
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare(secret, b) == 1
+}
@@ -0,0 +1,54 @@
+package filter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestCSRF(t *testing.T) {
+	s := web.Default()
+	s.Use(NewCSRF())
+	s.Get("/form", func(ctx web.Context) error { return ctx.Text(CSRFToken(ctx)) })
+	s.Post("/submit", func(ctx web.Context) error { return ctx.Text("ok") })
+	s.Post("/hook", func(ctx web.Context) error { return ctx.Text("ok") }, web.WithOption("csrf", "off"))
+
+	// fetch token and cookie
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/form", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cookie := rec.Result().Cookies()[0]
+	token := rec.Body.String()
+	assert.NotNil(t, cookie)
+
+	post := func(path, header, field string, cookie *http.Cookie) int {
+		form := url.Values{}
+		if field != "" {
+			form.Set("_csrf", field)
+		}
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set(web.HeaderContentType, web.MIMEApplicationForm)
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, post("/submit", token, "", cookie))
+	assert.Equal(t, http.StatusOK, post("/submit", "", token, cookie))
+	assert.Equal(t, http.StatusOK, post("/submit", cookie.Value, "", cookie))
+	assert.Equal(t, http.StatusForbidden, post("/submit", "", "", cookie))
+	assert.Equal(t, http.StatusForbidden, post("/submit", token, "", nil))
+	assert.Equal(t, http.StatusForbidden, post("/submit", token[1:], "", cookie))
+	assert.Equal(t, http.StatusOK, post("/hook", "", "", nil))
+}
@@ -0,0 +1,103 @@
+package filter
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cuigh/auxo/net/web"
+)
+
+// IPFilter is a filter which allows or denies requests by client IP, which is resolved by `web.Context.RealIP`.
+// Deny list takes precedence over allow list.
+type IPFilter struct {
+	// Allow is a list of CIDRs or IPs which can access, all IPs not denied can access if it is empty.
+	// Optional. Default value []string{}.
+	Allow []string `json:"allow"`
+
+	// Deny is a list of CIDRs or IPs which can't access.
+	// Optional. Default value []string{}.
+	Deny []string `json:"deny"`
+
+	once  sync.Once
+	rules atomic.Value // *ipRules
+}
+
+// ipRules holds both lists, so they are swapped together on Reload.
+type ipRules struct {
+	allow *web.IPSet
+	deny  *web.IPSet
+}
+
+func newIPRules(allow, deny []string) (r *ipRules, err error) {
+	r = &ipRules{}
+	if r.allow, err = web.NewIPSet(allow...); err != nil {
+		return nil, err
+	}
+	if r.deny, err = web.NewIPSet(deny...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewIPFilter returns an IPFilter instance.
+func NewIPFilter(allow, deny []string) (*IPFilter, error) {
+	f := &IPFilter{Allow: allow, Deny: deny}
+	var err error
+	f.once.Do(func() {
+		err = f.init()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload replaces allow and deny lists at runtime, lists are not changed if any of them is invalid.
+func (f *IPFilter) Reload(allow, deny []string) error {
+	f.once.Do(f.mustInit)
+
+	r, err := newIPRules(allow, deny)
+	if err != nil {
+		return err
+	}
+	f.rules.Store(r)
+	return nil
+}
+
+// Apply implements `web.Filter` interface.
+func (f *IPFilter) Apply(next web.HandlerFunc) web.HandlerFunc {
+	f.once.Do(f.mustInit)
+	return func(ctx web.Context) error {
+		if !f.Accept(net.ParseIP(ctx.RealIP())) {
+			return web.NewError(http.StatusForbidden)
+		}
+		return next(ctx)
+	}
+}
+
+// Accept reports whether the ip can access.
+func (f *IPFilter) Accept(ip net.IP) bool {
+	f.once.Do(f.mustInit)
+	r := f.rules.Load().(*ipRules)
+	if r.deny.Contains(ip) {
+		return false
+	}
+	return r.allow.Len() == 0 || r.allow.Contains(ip)
+}
+
+func (f *IPFilter) init() error {
+	r, err := newIPRules(f.Allow, f.Deny)
+	if err != nil {
+		return err
+	}
+	f.rules.Store(r)
+	return nil
+}
+
+func (f *IPFilter) mustInit() {
+	if err := f.init(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,36 @@
+package filter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestIPFilter(t *testing.T) {
+	f, err := NewIPFilter([]string{"10.0.0.0/8"}, []string{"10.0.0.2"})
+	assert.NoError(t, err)
+
+	s := web.Default()
+	s.Use(f)
+	s.Get("/", func(ctx web.Context) error { return ctx.Text("ok") })
+
+	check := func(remote string, code int) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remote + ":1234"
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.Equal(t, code, w.Code, remote)
+	}
+	check("10.0.0.1", http.StatusOK)
+	check("10.0.0.2", http.StatusForbidden)
+	check("11.0.0.1", http.StatusForbidden)
+
+	assert.Error(t, f.Reload([]string{"bad"}, nil))
+	check("11.0.0.1", http.StatusForbidden)
+	assert.NoError(t, f.Reload(nil, []string{"10.0.0.1"}))
+	check("10.0.0.1", http.StatusForbidden)
+	check("11.0.0.1", http.StatusOK)
+}
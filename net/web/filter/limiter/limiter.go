@@ -25,6 +25,7 @@ func HandlerName(ctx web.Context) string {
 	return ctx.Handler().Name()
 }
 
+// IP returns client IP resolved by `web.Context.RealIP`, forwarding headers are only honored for trusted proxies.
 func IP(ctx web.Context) string {
 	return ctx.RealIP()
 }
@@ -0,0 +1,30 @@
+package filter
+
+import (
+	"time"
+
+	"github.com/cuigh/auxo/net/web"
+)
+
+// Timeout is a filter which limits the handling time of requests, see web.TimeoutHandler for details.
+// Handlers with their own timeout declared by web.WithTimeout are not affected.
+type Timeout struct {
+	// Timeout is the max duration to handle a request, zero means no timeout.
+	Timeout time.Duration `json:"timeout"`
+}
+
+// NewTimeout returns a Timeout instance.
+func NewTimeout(d time.Duration) *Timeout {
+	return &Timeout{Timeout: d}
+}
+
+// Apply implements `web.Filter` interface.
+func (t *Timeout) Apply(next web.HandlerFunc) web.HandlerFunc {
+	h := web.TimeoutHandler(next, t.Timeout)
+	return func(ctx web.Context) error {
+		if ctx.Handler().Timeout() > 0 {
+			return next(ctx)
+		}
+		return h(ctx)
+	}
+}
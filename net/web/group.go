@@ -9,11 +9,12 @@ type Group struct {
 	prefix  string
 	filters []Filter
 	server  *Server
+	host    *vhost
 }
 
 // Group creates a new router group.
 func (g *Group) Group(prefix string, filters ...Filter) *Group {
-	return g.server.Group(g.prefix+prefix, g.mergeFilters(filters)...)
+	return &Group{prefix: g.prefix + prefix, filters: g.mergeFilters(filters), server: g.server, host: g.host}
 }
 
 // Use adds filters to the group routes.
@@ -86,22 +87,28 @@ func (g *Group) Match(methods []string, path string, handler HandlerFunc, opts .
 // Handle registers routes from controller.
 // It panics if controller's Kind is not Struct.
 func (g *Group) Handle(path string, controller interface{}, filters ...Filter) {
-	g.server.Handle(g.prefix+path, controller, g.mergeFilters(filters)...)
+	g.server.handle(g.host, g.prefix+path, controller, g.mergeFilters(filters)...)
+}
+
+// WebSocket registers a WebSocket route with server's WebSocket options.
+func (g *Group) WebSocket(path string, h WebSocketHandler, opts ...HandlerOption) {
+	g.add(path, WrapWebSocket(h, g.server.cfg.WebSocket), opts, http.MethodGet)
 }
 
 // Static serves static files from a custom file system.
 func (g *Group) Static(prefix string, fs http.FileSystem, fallback string, filters ...Filter) {
-	g.server.Static(g.prefix+prefix, fs, fallback, g.mergeFilters(filters)...)
+	g.server.static(g.host, g.prefix+prefix, fs, fallback, g.mergeFilters(filters)...)
 }
 
 // File registers a route in order to server a single file of the local filesystem.
 func (g *Group) File(path string, fs http.FileSystem, name string, filters ...Filter) {
-	g.server.File(g.prefix+path, fs, name, g.mergeFilters(filters)...)
+	info := newHandlerInfo(WrapFile(fs, name), nil, g.mergeFilters(filters)...)
+	g.server.registerInfo(g.host, g.prefix+path, info, http.MethodGet)
 }
 
 func (g *Group) add(path string, handler HandlerFunc, opts []HandlerOption, methods ...string) {
 	info := newHandlerInfo(handler, opts, g.filters...)
-	g.server.registerInfo(g.prefix+path, info, methods...)
+	g.server.registerInfo(g.host, g.prefix+path, info, methods...)
 }
 
 func (g *Group) mergeFilters(filters []Filter) []Filter {
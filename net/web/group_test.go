@@ -2,6 +2,7 @@ package web
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/cuigh/auxo/test/assert"
@@ -30,8 +31,8 @@ func TestGroup(t *testing.T) {
 		g.Trace("/", h)
 		//g.Any("/", h)
 		//g.Match([]string{http.MethodGet, http.MethodPost}, "/", h)
-		g.Static("/static", "static")
-		g.File("/favicon.ico", "favicon.ico")
+		g.Static("/static", http.Dir("static"), "")
+		g.File("/favicon.ico", http.Dir("."), "favicon.ico")
 	}
 
 	ctx := s.AcquireContext(nil, nil)
@@ -41,3 +42,79 @@ func TestGroup(t *testing.T) {
 		assert.False(t, tsr)
 	}
 }
+
+func TestServer_Host(t *testing.T) {
+	s := Default()
+	text := func(s string) HandlerFunc {
+		return func(ctx Context) error { return ctx.Text(s) }
+	}
+	s.Get("/users", text("default"))
+	api := s.Host("api.example.com")
+	api.Get("/users", text("api"), WithName("api.users"))
+	api.Get("/users/:id", text("api-user"), WithName("api.user"))
+	api.Header("Accept-Version", "2").Get("/users", text("api-v2"))
+	s.Host("*.example.com").Group("/v1").Get("/users", text("wildcard"))
+
+	cases := []struct {
+		Host    string
+		Path    string
+		Version string
+		Body    string
+	}{
+		{"example.org", "/users", "", "default"},
+		{"API.example.com:8080", "/users", "", "api"},
+		{"api.example.com", "/users", "2", "api-v2"},
+		{"api.example.com", "/users/1", "2", "api-user"},
+		{"www.example.com", "/v1/users", "", "wildcard"},
+		{"www.example.com", "/users", "", "default"},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(http.MethodGet, c.Path, nil)
+		req.Host = c.Host
+		if c.Version != "" {
+			req.Header.Set("Accept-Version", c.Version)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, c.Body, rec.Body.String())
+	}
+
+	assert.Equal(t, "//api.example.com/users/1", s.URL("api.user", 1))
+	assert.Equal(t, "/users", s.URL("GET:/users"))
+	assert.Equal(t, "//api.example.com/users", s.URL("GET:api.example.com[Accept-Version=2]/users"))
+	assert.Equal(t, "/v1/users", s.URL("GET:*.example.com/v1/users"))
+}
+
+func TestServer_HostFallback(t *testing.T) {
+	s := New(&Options{MethodNotAllowed: true})
+	text := func(s string) HandlerFunc {
+		return func(ctx Context) error { return ctx.Text(s) }
+	}
+	s.Post("/users", text("create"))
+	s.Group("").Header("Accept-Version", "2").Get("/users", text("list-v2"))
+
+	cases := []struct {
+		Method  string
+		Version string
+		Code    int
+		Body    string
+	}{
+		{http.MethodPost, "2", http.StatusOK, "create"},
+		{http.MethodGet, "2", http.StatusOK, "list-v2"},
+		{http.MethodPost, "", http.StatusOK, "create"},
+		{http.MethodGet, "", http.StatusMethodNotAllowed, ""},
+		{http.MethodDelete, "2", http.StatusMethodNotAllowed, ""},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(c.Method, "/users", nil)
+		if c.Version != "" {
+			req.Header.Set("Accept-Version", c.Version)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, c.Code, rec.Code)
+		if c.Body != "" {
+			assert.Equal(t, c.Body, rec.Body.String())
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"io/fs"
 	"net/http"
+	"path"
+	"time"
 
 	"github.com/cuigh/auxo/data"
 )
@@ -55,6 +57,48 @@ func WrapFileSystem(sys http.FileSystem, fallback string) HandlerFunc {
 	}
 }
 
+// precompressed serves the precompressed sibling(.br/.gz) of a static file if client accepts it.
+func precompressed(sys http.FileSystem, next HandlerFunc) HandlerFunc {
+	encodings := []struct {
+		name string
+		ext  string
+	}{
+		{"br", ".br"},
+		{"gzip", ".gz"},
+	}
+	return func(c Context) error {
+		r := c.Request()
+		accept := r.Header.Get(HeaderAcceptEncoding)
+		if accept == "" {
+			return next(c)
+		}
+
+		name := path.Clean("/" + r.URL.Path)
+		for _, enc := range encodings {
+			if AcceptEncoding(accept, enc.name) == "" {
+				continue
+			}
+
+			f, err := sys.Open(name + enc.ext)
+			if err != nil {
+				continue
+			}
+			fi, err := f.Stat()
+			if err != nil || fi.IsDir() {
+				f.Close()
+				continue
+			}
+
+			h := c.Response().Header()
+			h.Add(HeaderVary, HeaderAcceptEncoding)
+			h.Set(HeaderContentEncoding, enc.name)
+			http.ServeContent(c.Response(), r, path.Base(name), fi.ModTime(), f)
+			return f.Close()
+		}
+		return next(c)
+	}
+}
+
 type fallbackFileSystem struct {
 	http.FileSystem
 	fallback string
@@ -111,6 +155,12 @@ type HandlerInfo interface {
 	Name() string
 	Authorize() string
 	Option(name string) string
+	// Request returns the bound request type declared by WithRequest.
+	Request() interface{}
+	// Responses returns response types declared by WithResponse, keyed by status code.
+	Responses() map[int]interface{}
+	// Timeout returns the timeout declared by WithTimeout, zero means no timeout.
+	Timeout() time.Duration
 }
 
 const (
@@ -124,6 +174,9 @@ type handlerInfo struct {
 	name      string
 	authorize string
 	options   data.Options
+	request   interface{}
+	responses map[int]interface{}
+	timeout   time.Duration
 }
 
 func newHandlerInfo(handler HandlerFunc, opts []HandlerOption, filters ...Filter) *handlerInfo {
@@ -153,6 +206,18 @@ func (h *handlerInfo) Option(name string) string {
 	return h.options.Get(name)
 }
 
+func (h *handlerInfo) Request() interface{} {
+	return h.request
+}
+
+func (h *handlerInfo) Responses() map[int]interface{} {
+	return h.responses
+}
+
+func (h *handlerInfo) Timeout() time.Duration {
+	return h.timeout
+}
+
 func (h *handlerInfo) addOption(name, value string) {
 	h.options = append(h.options, data.Option{Name: name, Value: value})
 }
@@ -190,3 +255,28 @@ func WithOption(name, value string) HandlerOption {
 		info.options = append(info.options, data.Option{Name: name, Value: value})
 	}
 }
+
+// WithRequest declares the type bound from request, e.g. `web.WithRequest(User{})`, it is used to generate API documents.
+func WithRequest(v interface{}) HandlerOption {
+	return func(info *handlerInfo) {
+		info.request = v
+	}
+}
+
+// WithResponse declares the response type of status code, it is used to generate API documents.
+func WithResponse(status int, v interface{}) HandlerOption {
+	return func(info *handlerInfo) {
+		if info.responses == nil {
+			info.responses = make(map[int]interface{})
+		}
+		info.responses[status] = v
+	}
+}
+
+// WithTimeout sets timeout of handler, a deadline is attached to `Request().Context()` and
+// ErrTimeout is returned if handler doesn't finish in time. See TimeoutHandler for details.
+func WithTimeout(d time.Duration) HandlerOption {
+	return func(info *handlerInfo) {
+		info.timeout = d
+	}
+}
@@ -0,0 +1,30 @@
+package health
+
+import (
+	"context"
+
+	"github.com/cuigh/auxo/db/gsd"
+	"github.com/cuigh/auxo/db/redis"
+	"github.com/cuigh/auxo/net/rpc"
+)
+
+// DB returns a checker which pings the database.
+func DB(db gsd.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return db.Ping(ctx)
+	})
+}
+
+// Redis returns a checker which pings the redis server.
+func Redis(client redis.Client) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return client.Ping().Err()
+	})
+}
+
+// RPC returns a checker which checks whether a node of the RPC client is available.
+func RPC(client *rpc.Client) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return client.Ping()
+	})
+}
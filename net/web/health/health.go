@@ -0,0 +1,238 @@
+// Package health provides health, readiness and liveness endpoints for web server.
+//
+//	h := health.New(server)
+//	h.Ready("db", health.DB(db), health.WithTimeout(time.Second))
+//	h.Ready("redis", health.Redis(client), health.WithCache(5*time.Second))
+//	h.Live("self", health.CheckerFunc(func(ctx context.Context) error { return nil }))
+//
+// `/livez` runs liveness checks, `/readyz` runs readiness checks and `/healthz` runs all checks.
+// Readiness fails as soon as `Server.Close` is called, so load balancers stop sending requests first.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cuigh/auxo/net/web"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker checks whether a dependency or the application itself is healthy.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is an adapter to allow the use of ordinary functions as Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker interface.
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Option customizes a check.
+type Option func(c *check)
+
+// WithTimeout sets timeout of a check, it overrides `Options.Timeout`.
+func WithTimeout(d time.Duration) Option {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// WithCache caches result of a check for the duration, it is useful for expensive checks.
+func WithCache(d time.Duration) Option {
+	return func(c *check) {
+		c.ttl = d
+	}
+}
+
+// Options represents options of health endpoints.
+type Options struct {
+	// Prefix is the prefix of endpoint paths, like `/-` makes `/-/healthz`.
+	Prefix string
+
+	// Timeout is the default timeout of checks.
+	// Optional. Default value 5s.
+	Timeout time.Duration
+
+	// ShutdownDelay is the time to keep serving after readiness fails on `Server.Close`, it is applied
+	// if web.Options.ShutdownDelay is not set, see Server.DelayShutdown.
+	// Optional. Default value 5s.
+	ShutdownDelay time.Duration
+}
+
+// Result is the result of a check.
+type Result struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the response of health endpoints.
+type Report struct {
+	Status string             `json:"status"`
+	Checks map[string]*Result `json:"checks,omitempty"`
+}
+
+// Health is a registry of checks.
+type Health struct {
+	server  *web.Server
+	timeout time.Duration
+
+	locker sync.RWMutex
+	ready  []*check
+	live   []*check
+}
+
+// New creates a Health and registers endpoints on the server.
+func New(s *web.Server, opts ...Options) *Health {
+	var o Options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+	if o.ShutdownDelay <= 0 {
+		o.ShutdownDelay = 5 * time.Second
+	}
+	s.DelayShutdown(o.ShutdownDelay)
+
+	h := &Health{server: s, timeout: o.Timeout}
+	s.Get(o.Prefix+"/healthz", h.handler(true, true), web.WithName("health.healthz"),
+		web.WithAuthorize(web.AuthAnonymous), web.WithOption("openapi", "off"))
+	s.Get(o.Prefix+"/readyz", h.handler(true, false), web.WithName("health.readyz"),
+		web.WithAuthorize(web.AuthAnonymous), web.WithOption("openapi", "off"))
+	s.Get(o.Prefix+"/livez", h.handler(false, true), web.WithName("health.livez"),
+		web.WithAuthorize(web.AuthAnonymous), web.WithOption("openapi", "off"))
+	return h
+}
+
+// Ready adds a readiness check, failed readiness checks make load balancers stop routing traffic to the server.
+func (h *Health) Ready(name string, c Checker, opts ...Option) {
+	h.locker.Lock()
+	h.ready = append(h.ready, newCheck(name, c, opts))
+	h.locker.Unlock()
+}
+
+// Live adds a liveness check, failed liveness checks generally make the server restarted.
+func (h *Health) Live(name string, c Checker, opts ...Option) {
+	h.locker.Lock()
+	h.live = append(h.live, newCheck(name, c, opts))
+	h.locker.Unlock()
+}
+
+// Run runs checks concurrently and returns the report.
+func (h *Health) Run(ctx context.Context, ready, live bool) *Report {
+	var checks []*check
+	h.locker.RLock()
+	if ready {
+		checks = append(checks, h.ready...)
+	}
+	if live {
+		checks = append(checks, h.live...)
+	}
+	h.locker.RUnlock()
+
+	report := &Report{Status: StatusUp}
+	if len(checks) > 0 {
+		results := make([]*Result, len(checks))
+		var wg sync.WaitGroup
+		wg.Add(len(checks))
+		for i, c := range checks {
+			go func(i int, c *check) {
+				defer wg.Done()
+				results[i] = c.run(ctx, h.timeout)
+			}(i, c)
+		}
+		wg.Wait()
+
+		report.Checks = make(map[string]*Result, len(checks))
+		for i, c := range checks {
+			report.Checks[c.name] = results[i]
+			if results[i].Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}
+	}
+
+	if ready && h.server.Closing() {
+		report.Status = StatusDown
+	}
+	return report
+}
+
+func (h *Health) handler(ready, live bool) web.HandlerFunc {
+	return func(ctx web.Context) error {
+		report := h.Run(ctx.Request().Context(), ready, live)
+		ctx.SetHeader(web.HeaderCacheControl, "no-store")
+		if report.Status != StatusUp {
+			ctx.Status(http.StatusServiceUnavailable)
+		}
+		return ctx.JSON(report)
+	}
+}
+
+type check struct {
+	name    string
+	checker Checker
+	timeout time.Duration
+	ttl     time.Duration
+
+	locker  sync.Mutex
+	result  *Result
+	expires time.Time
+}
+
+func newCheck(name string, c Checker, opts []Option) *check {
+	chk := &check{name: name, checker: c}
+	for _, opt := range opts {
+		opt(chk)
+	}
+	return chk
+}
+
+func (c *check) run(ctx context.Context, timeout time.Duration) *Result {
+	if c.ttl > 0 {
+		c.locker.Lock()
+		defer c.locker.Unlock()
+		if c.result != nil && time.Now().Before(c.expires) {
+			return c.result
+		}
+	}
+
+	if c.timeout > 0 {
+		timeout = c.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	ch := make(chan error, 1)
+	go func() {
+		ch <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-ch:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	r := &Result{Status: StatusUp, Duration: time.Since(start).String()}
+	if err != nil {
+		r.Status, r.Error = StatusDown, err.Error()
+	}
+	if c.ttl > 0 {
+		c.result, c.expires = r, time.Now().Add(c.ttl)
+	}
+	return r
+}
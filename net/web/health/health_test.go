@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestHealth(t *testing.T) {
+	var calls int32
+	s := web.Default()
+	h := New(s, Options{Timeout: 50 * time.Millisecond})
+	h.Live("self", CheckerFunc(func(ctx context.Context) error { return nil }))
+	h.Ready("cached", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}), WithCache(time.Minute))
+
+	get := func(path string) (int, *Report) {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		r := &Report{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), r))
+		return rec.Code, r
+	}
+
+	code, r := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, len(r.Checks))
+	code, r = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusUp, r.Checks["cached"].Status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	h.Ready("broken", CheckerFunc(func(ctx context.Context) error { return errors.New("broken") }))
+	h.Ready("slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}))
+	code, r = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "broken", r.Checks["broken"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), r.Checks["slow"].Error)
+
+	code, _ = get("/livez")
+	assert.Equal(t, http.StatusOK, code)
+
+	s.Close(0)
+	code, r = get("/livez")
+	assert.Equal(t, http.StatusOK, code)
+	code, r = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusDown, r.Status)
+}
+
+func TestHealth_ShutdownDelay(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := l.Addr().String()
+	assert.NoError(t, l.Close())
+
+	s := web.New(&web.Options{Entries: []web.Entry{{Address: addr}}})
+	New(s, Options{ShutdownDelay: 500 * time.Millisecond})
+	go func() { _ = s.Serve() }()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: time.Second}
+	get := func(path string) (int, error) {
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	for i := 0; i < 100; i++ {
+		if code, _ := get("/readyz"); code == http.StatusOK {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Close(time.Second)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// readiness fails while requests are still served
+	code, err := get("/readyz")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	code, err = get("/livez")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+
+	<-done
+	_, err = get("/livez")
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/cuigh/auxo/net/web/router"
+)
+
+// vhost is a virtual host which has its own route tree, requests are dispatched to it by host and headers.
+type vhost struct {
+	host    string // lower case host name, `*.example.com` matches all sub domains of example.com
+	headers [][2]string
+	router  *router.Tree
+}
+
+func (h *vhost) match(host string, header http.Header) bool {
+	if h.host != "" {
+		if h.host[0] == '*' {
+			if !strings.HasSuffix(host, h.host[1:]) {
+				return false
+			}
+		} else if h.host != host {
+			return false
+		}
+	}
+
+	for _, kv := range h.headers {
+		v := header.Get(kv[0])
+		if v == "" || (kv[1] != "*" && kv[1] != v) {
+			return false
+		}
+	}
+	return true
+}
+
+// qualified reports whether URLs of routes can be qualified with host name.
+func (h *vhost) qualified() bool {
+	return h != nil && h.host != "" && h.host[0] != '*'
+}
+
+// String returns the host pattern and header matchers, like `api.example.com[Accept-Version=2]`.
+func (h *vhost) String() string {
+	var sb strings.Builder
+	sb.WriteString(h.host)
+	for _, kv := range h.headers {
+		sb.WriteString("[" + kv[0] + "=" + kv[1] + "]")
+	}
+	return sb.String()
+}
+
+func (h *vhost) equal(host string, headers [][2]string) bool {
+	if h.host != host || len(h.headers) != len(headers) {
+		return false
+	}
+	for i := range headers {
+		if h.headers[i] != headers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Host creates a router group whose routes only serve requests with matched host, port of host is ignored.
+// The pattern is a host name like `api.example.com`, or a wildcard like `*.example.com` which matches all sub domains.
+// Requests which match no route of any virtual host fall back to routes registered on server directly.
+func (s *Server) Host(pattern string, filters ...Filter) *Group {
+	g := &Group{server: s, host: s.addHost(strings.ToLower(pattern), nil)}
+	g.Use(filters...)
+	return g
+}
+
+// Header creates a router group whose routes only serve requests with matched header, it is useful for API versioning:
+//
+//	v2 := s.Host("api.example.com").Header("Accept-Version", "2")
+//
+// The value `*` matches any non-empty header value. Requests which match no route of the group fall back to
+// routes of parent host, so only changed APIs need to be registered on the new version.
+func (g *Group) Header(name, value string) *Group {
+	var (
+		host    string
+		headers [][2]string
+	)
+	if g.host != nil {
+		host = g.host.host
+		headers = append(headers, g.host.headers...)
+	}
+	headers = append(headers, [2]string{http.CanonicalHeaderKey(name), value})
+
+	return &Group{
+		prefix:  g.prefix,
+		filters: g.mergeFilters(nil),
+		server:  g.server,
+		host:    g.server.addHost(host, headers),
+	}
+}
+
+func (s *Server) addHost(host string, headers [][2]string) *vhost {
+	for _, h := range s.hosts {
+		if h.equal(host, headers) {
+			return h
+		}
+	}
+
+	h := &vhost{
+		host:    host,
+		headers: headers,
+		router:  router.New(router.Options{IgnoreCase: s.cfg.IgnoreCase, DecodeParam: s.cfg.DecodeParam}),
+	}
+	s.hosts = append(s.hosts, h)
+	// more specific hosts take precedence: exact host > wildcard host > any host, then more headers first
+	sort.SliceStable(s.hosts, func(i, j int) bool {
+		if pi, pj := hostPriority(s.hosts[i].host), hostPriority(s.hosts[j].host); pi != pj {
+			return pi < pj
+		}
+		return len(s.hosts[i].headers) > len(s.hosts[j].headers)
+	})
+	return h
+}
+
+func hostPriority(host string) int {
+	switch {
+	case host == "":
+		return 2
+	case host[0] == '*':
+		return 1
+	default:
+		return 0
+	}
+}
+
+// find searches route in virtual hosts first, and then in the default route tree. A virtual host is skipped
+// if it has no handler for the method, but its result is kept to respond 405 or redirect if nothing else matches.
+func (s *Server) find(r *http.Request, path string, paramValues []string) (route router.Route, tsr bool) {
+	var (
+		fallback    router.Route
+		fallbackTSR bool
+	)
+	if len(s.hosts) > 0 {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(host)
+
+		for _, h := range s.hosts {
+			if !h.match(host, r.Header) {
+				continue
+			}
+
+			route, tsr = h.router.Find(r.Method, path, paramValues)
+			if route != nil && route.Handler() != nil {
+				return route, false
+			}
+			if fallback == nil && !fallbackTSR && (route != nil || tsr) {
+				fallback, fallbackTSR = route, tsr
+			}
+		}
+	}
+
+	route, tsr = s.router.Find(r.Method, path, paramValues)
+	if (route == nil || route.Handler() == nil) && (fallback != nil || fallbackTSR) {
+		return fallback, fallbackTSR
+	}
+	return
+}
+
+func (s *Server) maxParam() int {
+	n := s.router.MaxParam()
+	for _, h := range s.hosts {
+		if m := h.router.MaxParam(); m > n {
+			n = m
+		}
+	}
+	return n
+}
+
+type namedRoute struct {
+	router.Route
+	host *vhost
+}
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cuigh/auxo/errors"
+)
+
+// IPSet is a set of IP networks, it is safe for concurrent use and can be reloaded at runtime.
+type IPSet struct {
+	nets atomic.Value // []*net.IPNet
+}
+
+// NewIPSet creates an IPSet with CIDRs like `10.0.0.0/8`, single IPs like `127.0.0.1` are also accepted.
+func NewIPSet(cidrs ...string) (*IPSet, error) {
+	s := &IPSet{}
+	if err := s.Reset(cidrs...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reset replaces all networks of the set, the set is not changed if any CIDR is invalid.
+func (s *IPSet) Reset(cidrs ...string) error {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return errors.Format("web: invalid IP: %s", cidr)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				nets = append(nets, &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)})
+			} else {
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+			}
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return errors.Format("web: invalid CIDR: %s", cidr)
+		}
+		nets = append(nets, n)
+	}
+	s.nets.Store(nets)
+	return nil
+}
+
+// Len returns the count of networks.
+func (s *IPSet) Len() int {
+	nets, _ := s.nets.Load().([]*net.IPNet)
+	return len(nets)
+}
+
+// Contains reports whether ip is in any network of the set.
+func (s *IPSet) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	nets, _ := s.nets.Load().([]*net.IPNet)
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// realIP returns the client address of request. Forwarding headers are only used if the request
+// comes from a trusted proxy, and addresses in them are checked from right to left, the first
+// untrusted one is the client. `Forwarded` takes precedence over `X-Forwarded-For` and `X-Real-IP`.
+func realIP(r *http.Request, trusted *IPSet) string {
+	ra := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ra); err == nil {
+		ra = host
+	}
+	if trusted.Len() == 0 || !trusted.Contains(net.ParseIP(ra)) {
+		return ra
+	}
+
+	var hops []string
+	if values := r.Header.Values(HeaderForwarded); len(values) > 0 {
+		hops = parseForwarded(values)
+	} else if values = r.Header.Values(HeaderXForwardedFor); len(values) > 0 {
+		for _, v := range values {
+			for _, hop := range strings.Split(v, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+	} else if ip := strings.TrimSpace(r.Header.Get(HeaderXRealIP)); net.ParseIP(ip) != nil {
+		return ip
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// hops before an invalid one can't be verified
+			break
+		}
+		ra = hops[i]
+		if !trusted.Contains(ip) {
+			break
+		}
+	}
+	return ra
+}
+
+// parseForwarded extracts addresses of `for` parameters from `Forwarded` headers (RFC 7239),
+// ports and brackets of IPv6 are removed, obfuscated identifiers are kept as they are.
+func parseForwarded(values []string) (hops []string) {
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			hop := ""
+			for _, pair := range strings.Split(elem, ";") {
+				pair = strings.TrimSpace(pair)
+				if len(pair) > 4 && strings.EqualFold(pair[:4], "for=") {
+					hop = strings.Trim(pair[4:], `"`)
+					break
+				}
+			}
+			if strings.HasPrefix(hop, "[") {
+				if i := strings.IndexByte(hop, ']'); i > 0 {
+					hop = hop[1:i]
+				}
+			} else if host, _, err := net.SplitHostPort(hop); err == nil {
+				hop = host
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := NewIPSet("10.0.0.0/8", "192.168.1.1")
+	assert.NoError(t, err)
+
+	cases := []struct {
+		Remote  string
+		Headers map[string]string
+		IP      string
+	}{
+		{"1.1.1.1:1234", map[string]string{HeaderXForwardedFor: "2.2.2.2"}, "1.1.1.1"},
+		{"1.1.1.1:1234", map[string]string{HeaderXRealIP: "2.2.2.2"}, "1.1.1.1"},
+		{"10.0.0.1:1234", map[string]string{HeaderXRealIP: "2.2.2.2"}, "2.2.2.2"},
+		{"10.0.0.1:1234", map[string]string{HeaderXForwardedFor: "3.3.3.3, 2.2.2.2, 192.168.1.1"}, "2.2.2.2"},
+		{"10.0.0.1:1234", map[string]string{HeaderXForwardedFor: "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"10.0.0.1:1234", map[string]string{HeaderXForwardedFor: "2.2.2.2, bad"}, "10.0.0.1"},
+		{"10.0.0.1:1234", map[string]string{HeaderForwarded: `for="[2001:db8::1]:80";proto=https, for=10.0.0.2`, HeaderXForwardedFor: "3.3.3.3"}, "2001:db8::1"},
+		{"10.0.0.1:1234", map[string]string{HeaderForwarded: "for=2.2.2.2:8080"}, "2.2.2.2"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.Remote
+		for k, v := range c.Headers {
+			r.Header.Set(k, v)
+		}
+		assert.Equal(t, c.IP, realIP(r, trusted), c.Headers)
+	}
+}
+
+func TestIPSet(t *testing.T) {
+	s, err := NewIPSet("10.0.0.0/8", "::1")
+	assert.NoError(t, err)
+	assert.True(t, s.Contains(net.ParseIP("10.1.2.3")))
+	assert.True(t, s.Contains(net.ParseIP("::1")))
+	assert.False(t, s.Contains(net.ParseIP("11.0.0.1")))
+
+	assert.Error(t, s.Reset("10.0.0.0/33"))
+	assert.Equal(t, 2, s.Len())
+	assert.NoError(t, s.Reset("11.0.0.0/8"))
+	assert.True(t, s.Contains(net.ParseIP("11.0.0.1")))
+	assert.False(t, s.Contains(net.ParseIP("10.1.2.3")))
+}
@@ -0,0 +1,481 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cuigh/auxo/data/valid"
+	"github.com/cuigh/auxo/ext/texts"
+	"github.com/cuigh/auxo/net/web"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	nameRegex    = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// Generate creates an OpenAPI document from routes of the default host of server.
+// Handlers can be described with options: summary, desc, tags (comma separated), deprecated,
+// and excluded by option `openapi` with value "off".
+func Generate(s *web.Server, info Info) *Document {
+	return GenerateHost(s, info, "")
+}
+
+// GenerateHost is like Generate, but it only includes routes of the virtual host, a path can be registered
+// on several hosts, so every host has its own document. host is the pattern passed to `Server.Host` with
+// header matchers if any, e.g. `api.example.com[Accept-Version=2]`.
+func GenerateHost(s *web.Server, info Info, host string) *Document {
+	g := &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+
+	s.Walk(func(hp, method, path string, h web.HandlerInfo) {
+		if hp != host || h.Option("openapi") == "off" {
+			return
+		}
+
+		p, params := convertPath(path)
+		item := doc.Paths[p]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[p] = item
+		}
+		item.set(method, g.operation(method, params, h))
+	})
+
+	if len(g.schemas) > 0 {
+		doc.Components = &Components{Schemas: g.schemas}
+	}
+	return doc
+}
+
+// convertPath converts route path like `/users/:id` or `/users/{id:int}` to `/users/{id}`.
+func convertPath(path string) (p string, params []*Parameter) {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if seg == "" {
+			continue
+		}
+
+		var name, expr string
+		switch seg[0] {
+		case ':':
+			name = seg[1:]
+		case '{':
+			name = strings.TrimSuffix(seg[1:], "}")
+			if j := strings.IndexByte(name, ':'); j >= 0 {
+				name, expr = name[:j], name[j+1:]
+			}
+		case '*':
+			if name = seg[1:]; name == "" {
+				name = "path"
+			}
+		default:
+			continue
+		}
+		segments[i] = "{" + name + "}"
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: constraintSchema(expr)})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func constraintSchema(expr string) *Schema {
+	switch expr {
+	case "":
+		return &Schema{Type: "string"}
+	case "int":
+		return &Schema{Type: "integer"}
+	case "uint":
+		return &Schema{Type: "integer", Minimum: new(float64)}
+	case "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	case "alpha":
+		return &Schema{Type: "string", Pattern: "^[a-zA-Z]+$"}
+	case "alnum":
+		return &Schema{Type: "string", Pattern: "^[a-zA-Z0-9]+$"}
+	default:
+		return &Schema{Type: "string", Pattern: "^(?:" + expr + ")$"}
+	}
+}
+
+func (item *PathItem) set(method string, op *Operation) {
+	switch method {
+	case http.MethodGet:
+		item.Get = op
+	case http.MethodPut:
+		item.Put = op
+	case http.MethodPost:
+		item.Post = op
+	case http.MethodDelete:
+		item.Delete = op
+	case http.MethodOptions:
+		item.Options = op
+	case http.MethodHead:
+		item.Head = op
+	case http.MethodPatch:
+		item.Patch = op
+	case http.MethodTrace:
+		item.Trace = op
+	}
+}
+
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func (g *generator) operation(method string, pathParams []*Parameter, h web.HandlerInfo) *Operation {
+	op := &Operation{
+		OperationID: h.Name(),
+		Summary:     h.Option("summary"),
+		Description: h.Option("desc"),
+		Deprecated:  h.Option("deprecated") == "true",
+		Responses:   make(map[string]*Response),
+	}
+	if tags := h.Option("tags"); tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			op.Tags = append(op.Tags, strings.TrimSpace(tag))
+		}
+	}
+
+	if req := h.Request(); req != nil {
+		g.request(op, method, reflect.TypeOf(req))
+	}
+
+	// path parameters which are not declared by request type
+	var params []*Parameter
+	for _, p := range pathParams {
+		if !op.hasParam(p.Name, "path") {
+			params = append(params, p)
+		}
+	}
+	op.Parameters = append(params, op.Parameters...)
+
+	for status, v := range h.Responses() {
+		r := &Response{Description: http.StatusText(status)}
+		if v != nil {
+			r.Content = map[string]*MediaType{
+				web.MIMEApplicationJSON: {Schema: g.schema(reflect.TypeOf(v))},
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = r
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = &Response{Description: http.StatusText(http.StatusOK)}
+	}
+	return op
+}
+
+func (op *Operation) hasParam(name, in string) bool {
+	for _, p := range op.Parameters {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// request generates parameters and body from bound type, fields are placed according to `bind` tag.
+func (g *generator) request(op *Operation, method string, t reflect.Type) {
+	t = indirect(t)
+	hasBody := method != http.MethodGet && method != http.MethodHead && method != http.MethodDelete
+	if t.Kind() != reflect.Struct {
+		if hasBody {
+			op.RequestBody = jsonBody(g.schema(t))
+		}
+		return
+	}
+
+	var (
+		body      = &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		form      = &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		multipart bool
+		explicit  bool
+	)
+	visitFields(t, func(sf *reflect.StructField) {
+		rules, _ := valid.ParseRules(sf.Tag.Get(valid.Tag))
+		_, required := rules["required"]
+
+		name, in := bindSource(sf)
+		switch in {
+		case "path", "query", "header", "cookie":
+			explicit = true
+			s := g.schema(sf.Type)
+			applyRules(s, rules)
+			op.Parameters = append(op.Parameters, &Parameter{Name: name, In: in, Required: required || in == "path", Schema: s})
+		case "file":
+			explicit, multipart = true, true
+			form.Properties[name] = &Schema{Type: "string", Format: "binary"}
+			if required {
+				form.Required = append(form.Required, name)
+			}
+		default:
+			if !hasBody {
+				s := g.schema(sf.Type)
+				applyRules(s, rules)
+				op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "query", Required: required, Schema: s})
+				return
+			}
+
+			s := g.schema(sf.Type)
+			applyRules(s, rules)
+			form.Properties[name] = s
+			if required {
+				form.Required = append(form.Required, name)
+			}
+			if jn := jsonName(sf); jn != "" {
+				body.Properties[jn] = s
+				if required {
+					body.Required = append(body.Required, jn)
+				}
+			}
+		}
+	})
+
+	if !hasBody {
+		return
+	}
+	if multipart {
+		op.RequestBody = &RequestBody{Content: map[string]*MediaType{web.MIMEMultipartForm: {Schema: form}}}
+	} else if !explicit {
+		// the whole type is bound from body, so it can be shared
+		op.RequestBody = jsonBody(g.schema(t))
+	} else if len(body.Properties) > 0 {
+		op.RequestBody = jsonBody(body)
+	}
+}
+
+func jsonBody(s *Schema) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{web.MIMEApplicationJSON: {Schema: s}},
+	}
+}
+
+// bindSource returns parameter name and location of field by `bind` tag, see web.Binder.
+func bindSource(sf *reflect.StructField) (name, in string) {
+	tag := sf.Tag.Get("bind")
+	if tag == "" {
+		return texts.Rename(sf.Name, texts.Lower), ""
+	}
+
+	for i, item := range strings.Split(tag, ",") {
+		pair := strings.SplitN(item, "=", 2)
+		if i == 0 {
+			name = pair[0]
+		}
+		if len(pair) == 2 {
+			if strings.HasPrefix(pair[1], "file") {
+				return pair[0], "file"
+			} else if pair[1] != "form" {
+				return pair[0], pair[1]
+			}
+		}
+	}
+	return
+}
+
+func jsonName(sf *reflect.StructField) string {
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return sf.Name
+}
+
+// visitFields visits exported fields of struct, fields of embedded structs are promoted.
+func visitFields(t reflect.Type, fn func(sf *reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Tag.Get("json") == "" {
+			if ft := indirect(sf.Type); ft.Kind() == reflect.Struct {
+				visitFields(ft, fn)
+				continue
+			}
+		}
+		if sf.PkgPath == "" {
+			fn(&sf)
+		}
+	}
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	t = indirect(t)
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// ref registers named struct type to components and returns a reference to it.
+func (g *generator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.name(t)
+		g.names[t] = name
+
+		// register before generating, so recursive types can refer to it
+		s := &Schema{}
+		g.schemas[name] = s
+		*s = *g.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *generator) name(t reflect.Type) string {
+	name := nameRegex.ReplaceAllString(t.Name(), "_")
+	if _, ok := g.schemas[name]; ok {
+		pkg := t.PkgPath()
+		if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+			pkg = pkg[i+1:]
+		}
+		name = pkg + "." + name
+		for i := 2; ; i++ {
+			if _, ok = g.schemas[name]; !ok {
+				break
+			}
+			name = pkg + "." + nameRegex.ReplaceAllString(t.Name(), "_") + strconv.Itoa(i)
+		}
+	}
+	return name
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	visitFields(t, func(sf *reflect.StructField) {
+		name := jsonName(sf)
+		if name == "" {
+			return
+		}
+
+		rules, _ := valid.ParseRules(sf.Tag.Get(valid.Tag))
+		ps := g.schema(sf.Type)
+		applyRules(ps, rules)
+		s.Properties[name] = ps
+		if _, ok := rules["required"]; ok {
+			s.Required = append(s.Required, name)
+		}
+	})
+	sort.Strings(s.Required)
+	return s
+}
+
+// applyRules converts validation rules to schema constraints.
+func applyRules(s *Schema, rules map[string]valid.Argument) {
+	if s.Ref != "" {
+		// siblings of $ref are ignored by OpenAPI 3.0
+		return
+	}
+
+	for name, arg := range rules {
+		switch name {
+		case "length":
+			min, max, single := bounds(arg)
+			minInt, maxInt := toInt(min, !single && arg.Left == valid.LeftOpen, 1), toInt(max, !single && arg.Right == valid.RightOpen, -1)
+			if s.Type == "array" {
+				s.MinItems, s.MaxItems = minInt, maxInt
+			} else {
+				s.MinLength, s.MaxLength = minInt, maxInt
+			}
+		case "range":
+			min, max, single := bounds(arg)
+			if min != nil {
+				s.Minimum, s.ExclusiveMinimum = min, !single && arg.Left == valid.LeftOpen
+			}
+			if max != nil {
+				s.Maximum, s.ExclusiveMaximum = max, !single && arg.Right == valid.RightOpen
+			}
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "ip", "ipv4", "ipv6":
+			s.Format = name
+		case "alpha":
+			s.Pattern = valid.PatternAlpha
+		case "regex":
+			s.Pattern = arg.Value
+		}
+	}
+}
+
+// bounds parses arguments like `5`, `3~8` or `~8`, single is true if there is only one value.
+func bounds(arg valid.Argument) (min, max *float64, single bool) {
+	parse := func(s string) *float64 {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return &f
+		}
+		return nil
+	}
+
+	pair := strings.SplitN(arg.Value, "~", 2)
+	if len(pair) == 1 {
+		v := parse(pair[0])
+		return v, v, true
+	}
+	return parse(pair[0]), parse(pair[1]), false
+}
+
+func toInt(f *float64, exclusive bool, delta int) *int {
+	if f == nil {
+		return nil
+	}
+	i := int(*f)
+	if exclusive {
+		i += delta
+	}
+	return &i
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,92 @@
+// Package openapi generates OpenAPI 3 documents from routes of web server.
+//
+// Request and response types are declared with handler options:
+//
+//	s.Post("/users", createUser, web.WithRequest(User{}), web.WithResponse(200, Result{}), web.WithOption("tags", "user"))
+//
+// Parameters are resolved by `bind` tags, and constraints are resolved by `valid` tags.
+package openapi
+
+import (
+	"html/template"
+	"sync"
+
+	"github.com/cuigh/auxo/net/web"
+)
+
+// Options represents options of API document.
+type Options struct {
+	// Info is the metadata of API.
+	Info Info
+
+	// Servers is a list of servers which provide the API.
+	Servers []*Server
+
+	// Host is the virtual host whose routes are documented, see GenerateHost.
+	// Optional. Default value "" means routes of the default host.
+	Host string
+
+	// Path is the route path of document.
+	// Optional. Default value "/openapi.json".
+	Path string
+
+	// UIPath is the route path of document UI page, the UI is disabled if it is empty.
+	UIPath string
+
+	// UIScript is the base URL of swagger-ui dist files.
+	// Optional. Default value "https://unpkg.com/swagger-ui-dist@5".
+	UIScript string
+}
+
+// Register registers routes which serve API document and UI page on server.
+// The document is generated on first request, so routes registered after calling Register are included.
+func Register(s *web.Server, opts Options) {
+	if opts.Path == "" {
+		opts.Path = "/openapi.json"
+	}
+	if opts.Info.Title == "" {
+		opts.Info.Title = "API"
+	}
+	if opts.Info.Version == "" {
+		opts.Info.Version = "1.0.0"
+	}
+	if opts.UIScript == "" {
+		opts.UIScript = "https://unpkg.com/swagger-ui-dist@5"
+	}
+
+	var (
+		once sync.Once
+		doc  *Document
+	)
+	s.Get(opts.Path, func(ctx web.Context) error {
+		once.Do(func() {
+			doc = GenerateHost(s, opts.Info, opts.Host)
+			doc.Servers = opts.Servers
+		})
+		return ctx.JSON(doc)
+	}, web.WithName("openapi.document"), web.WithAuthorize(web.AuthAnonymous), web.WithOption("openapi", "off"))
+
+	if opts.UIPath != "" {
+		s.Get(opts.UIPath, func(ctx web.Context) error {
+			ctx.SetContentType(web.MIMETextHTMLCharsetUTF8)
+			return uiTemplate.Execute(ctx.Response(), opts)
+		}, web.WithName("openapi.ui"), web.WithAuthorize(web.AuthAnonymous), web.WithOption("openapi", "off"))
+	}
+}
+
+var uiTemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{ .Info.Title }}</title>
+  <link rel="stylesheet" href="{{ .UIScript }}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{ .UIScript }}/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "{{ .Path }}", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`))
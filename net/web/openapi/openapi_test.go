@@ -0,0 +1,128 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+type User struct {
+	ID      int32     `json:"id"`
+	Name    string    `json:"name" valid:"required,length[3~20)"`
+	Email   string    `json:"email,omitempty" valid:"email"`
+	Age     int       `json:"age" valid:"range[0~150]"`
+	Friends []*User   `json:"friends,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+type UserQuery struct {
+	Name  string `valid:"length(~10]"`
+	Page  int    `bind:"page_index=query" valid:"required"`
+	Token string `bind:"X-Token=header"`
+}
+
+type UserUpdate struct {
+	ID   int    `bind:"id=path"`
+	Name string `json:"name"`
+}
+
+func TestGenerate(t *testing.T) {
+	s := web.Default()
+	noop := func(ctx web.Context) error { return nil }
+	s.Get("/users", noop, web.WithRequest(UserQuery{}), web.WithResponse(http.StatusOK, []User{}),
+		web.WithOption("tags", "user, admin"), web.WithOption("summary", "Search users"))
+	s.Post("/users", noop, web.WithRequest(&User{}), web.WithResponse(http.StatusCreated, User{}))
+	s.Put("/users/:id", noop, web.WithRequest(UserUpdate{}), web.WithResponse(http.StatusNoContent, nil))
+	s.Get("/files/*", noop)
+	s.Delete("/posts/{id:uuid}", noop)
+	s.Get("/internal", noop, web.WithOption("openapi", "off"))
+
+	doc := Generate(s, Info{Title: "test", Version: "1.0"})
+	assert.Equal(t, 4, len(doc.Paths))
+
+	op := doc.Paths["/users"].Get
+	assert.Equal(t, []string{"user", "admin"}, op.Tags)
+	assert.Equal(t, "Search users", op.Summary)
+	assert.Equal(t, 3, len(op.Parameters))
+	assert.Equal(t, "name", op.Parameters[0].Name)
+	assert.Equal(t, "query", op.Parameters[0].In)
+	assert.Equal(t, 10, *op.Parameters[0].Schema.MaxLength)
+	assert.Equal(t, "page_index", op.Parameters[1].Name)
+	assert.True(t, op.Parameters[1].Required)
+	assert.Equal(t, "header", op.Parameters[2].In)
+	assert.Equal(t, "array", op.Responses["200"].Content[web.MIMEApplicationJSON].Schema.Type)
+
+	op = doc.Paths["/users"].Post
+	assert.Equal(t, "#/components/schemas/User", op.RequestBody.Content[web.MIMEApplicationJSON].Schema.Ref)
+	assert.NotNil(t, op.Responses["201"])
+
+	user := doc.Components.Schemas["User"]
+	assert.Equal(t, []string{"name"}, user.Required)
+	assert.Equal(t, 3, *user.Properties["name"].MinLength)
+	assert.Equal(t, 19, *user.Properties["name"].MaxLength)
+	assert.Equal(t, "email", user.Properties["email"].Format)
+	assert.Equal(t, float64(150), *user.Properties["age"].Maximum)
+	assert.False(t, user.Properties["age"].ExclusiveMaximum)
+	assert.Equal(t, "#/components/schemas/User", user.Properties["friends"].Items.Ref)
+	assert.Equal(t, "date-time", user.Properties["created"].Format)
+
+	op = doc.Paths["/users/{id}"].Put
+	assert.Equal(t, 1, len(op.Parameters))
+	assert.Equal(t, "path", op.Parameters[0].In)
+	assert.Equal(t, "integer", op.Parameters[0].Schema.Type)
+	assert.NotNil(t, op.RequestBody.Content[web.MIMEApplicationJSON].Schema.Properties["name"])
+	assert.True(t, op.Responses["204"].Content == nil)
+
+	op = doc.Paths["/files/{path}"].Get
+	assert.Equal(t, "path", op.Parameters[0].Name)
+
+	op = doc.Paths["/posts/{id}"].Delete
+	assert.Equal(t, "uuid", op.Parameters[0].Schema.Format)
+}
+
+func TestGenerateHost(t *testing.T) {
+	s := web.Default()
+	noop := func(ctx web.Context) error { return nil }
+	s.Get("/users", noop, web.WithResponse(http.StatusOK, []User{}))
+	api := s.Host("api.example.com")
+	api.Get("/users", noop, web.WithResponse(http.StatusOK, User{}))
+	api.Header("Accept-Version", "2").Post("/users", noop)
+
+	doc := Generate(s, Info{Title: "test", Version: "1.0"})
+	assert.Equal(t, 1, len(doc.Paths))
+	assert.Equal(t, "array", doc.Paths["/users"].Get.Responses["200"].Content[web.MIMEApplicationJSON].Schema.Type)
+
+	doc = GenerateHost(s, Info{Title: "test", Version: "1.0"}, "api.example.com")
+	assert.Equal(t, 1, len(doc.Paths))
+	assert.Equal(t, "#/components/schemas/User", doc.Paths["/users"].Get.Responses["200"].Content[web.MIMEApplicationJSON].Schema.Ref)
+	assert.True(t, doc.Paths["/users"].Post == nil)
+
+	doc = GenerateHost(s, Info{Title: "test", Version: "1.0"}, "api.example.com[Accept-Version=2]")
+	assert.NotNil(t, doc.Paths["/users"].Post)
+	assert.True(t, doc.Paths["/users"].Get == nil)
+}
+
+func TestRegister(t *testing.T) {
+	s := web.Default()
+	Register(s, Options{UIPath: "/docs"})
+	s.Get("/ping", func(ctx web.Context) error { return ctx.Text("pong") })
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	doc := &Document{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, 1, len(doc.Paths))
+	assert.NotNil(t, doc.Paths["/ping"])
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
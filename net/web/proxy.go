@@ -0,0 +1,420 @@
+package web
+
+import (
+	scontext "context"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cuigh/auxo/errors"
+	"github.com/cuigh/auxo/net/rpc/resolver"
+	"github.com/cuigh/auxo/net/transport"
+)
+
+// ProxyOptions represents options of reverse proxy.
+type ProxyOptions struct {
+	// Targets are upstream URLs like `http://10.0.0.1:8080/api`, they are ignored if Resolver is set.
+	Targets []string
+
+	// Resolver discovers upstreams, URLs of addresses must be HTTP URLs like `http://10.0.0.1:8080`.
+	Resolver resolver.Resolver
+
+	// Balancer is the name of load balancing strategy, `random` and `round_robin` are built in,
+	// others can be added with RegisterProxyBalancer.
+	// Optional. Default value "round_robin".
+	Balancer string
+
+	// StripPrefix is removed from request path before it is joined to target path.
+	StripPrefix string
+
+	// PreserveHost keeps `Host` header of incoming request, otherwise host of target is used.
+	PreserveHost bool
+
+	// Rewrite modifies outgoing request after path is rewritten, it can change path, query and headers.
+	Rewrite func(ctx Context, r *http.Request)
+
+	// ModifyResponse modifies response from upstream before it is copied to client.
+	ModifyResponse func(r *http.Response) error
+
+	// Transport is used to send requests to upstreams.
+	// Optional. Default value is http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// FlushInterval is the interval to flush response body to client, a negative value means flushing
+	// immediately after each write. Streaming responses like `text/event-stream` are always flushed immediately.
+	FlushInterval time.Duration
+
+	// HealthCheck configures active health checks, it is disabled if Path is empty.
+	HealthCheck struct {
+		// Path is requested on each target, a target is healthy if status code is less than 500.
+		Path string
+		// Optional. Default value 10s.
+		Interval time.Duration
+		// Optional. Default value 2s.
+		Timeout time.Duration
+	}
+
+	// MaxFails is the count of consecutive failures after which a target is ejected for FailTimeout.
+	// Optional. Default value 3.
+	MaxFails int
+
+	// FailTimeout is the duration a failed target is ejected.
+	// Optional. Default value 30s.
+	FailTimeout time.Duration
+}
+
+// ProxyBalancer selects an upstream for each request of ReverseProxy.
+//
+// Balancers of RPC client can't be reused here, because they select connected `rpc.Node` which
+// can't be created for HTTP upstreams, so proxy balancers have their own registry with the same names.
+type ProxyBalancer interface {
+	// Select returns the index of selected target, targets are available upstreams and never empty.
+	Select(r *http.Request, targets []*url.URL) int
+}
+
+// ProxyBalancerFunc is an adapter to allow the use of ordinary functions as ProxyBalancer.
+type ProxyBalancerFunc func(r *http.Request, targets []*url.URL) int
+
+// Select implements ProxyBalancer interface.
+func (f ProxyBalancerFunc) Select(r *http.Request, targets []*url.URL) int {
+	return f(r, targets)
+}
+
+var proxyBalancers = map[string]func() ProxyBalancer{
+	"random": func() ProxyBalancer {
+		return ProxyBalancerFunc(func(_ *http.Request, targets []*url.URL) int {
+			return rand.Intn(len(targets))
+		})
+	},
+	"round_robin": func() ProxyBalancer {
+		var counter uint64
+		return ProxyBalancerFunc(func(_ *http.Request, targets []*url.URL) int {
+			i := atomic.AddUint64(&counter, 1) - 1
+			return int(i % uint64(len(targets)))
+		})
+	},
+}
+
+// RegisterProxyBalancer registers a balancer builder with name, builder is called once for each ReverseProxy.
+// It should be called at initialization, e.g. in `init` functions.
+func RegisterProxyBalancer(name string, builder func() ProxyBalancer) {
+	proxyBalancers[strings.ToLower(name)] = builder
+}
+
+// Proxy returns a handler which forwards requests to targets with round-robin balancing.
+// It has no health checks or resolver, so there is nothing to close, use NewProxy if they are required.
+func Proxy(targets ...string) HandlerFunc {
+	p, err := NewProxy(ProxyOptions{Targets: targets})
+	if err != nil {
+		panic(err)
+	}
+	return p.Handle
+}
+
+// ReverseProxy is an HTTP reverse proxy with load balancing, health checks and passive ejection.
+// WebSocket and streaming responses are supported. Filters of the route are applied before forwarding.
+type ReverseProxy struct {
+	opts     ProxyOptions
+	proxy    *httputil.ReverseProxy
+	client   *http.Client
+	balancer ProxyBalancer
+	targets  atomic.Value // []*upstream
+	done     chan struct{}
+	once     sync.Once
+}
+
+type upstream struct {
+	url     *url.URL
+	fails   int32
+	ejected int64 // unix nano until which upstream is ejected
+	down    int32 // set by active health checks
+}
+
+func (u *upstream) available(now int64) bool {
+	return atomic.LoadInt32(&u.down) == 0 && atomic.LoadInt64(&u.ejected) < now
+}
+
+type proxyState struct {
+	target *upstream
+	err    error
+}
+
+type proxyKey struct{}
+
+// NewProxy creates a ReverseProxy, active health checks start at once if they are enabled,
+// Close must be called to stop them when the proxy is no longer used.
+func NewProxy(opts ProxyOptions) (*ReverseProxy, error) {
+	if opts.Balancer == "" {
+		opts.Balancer = "round_robin"
+	}
+	builder := proxyBalancers[strings.ToLower(opts.Balancer)]
+	if builder == nil {
+		return nil, errors.Format("web: unknown proxy balancer: %s", opts.Balancer)
+	}
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport
+	}
+	if opts.MaxFails <= 0 {
+		opts.MaxFails = 3
+	}
+	if opts.FailTimeout <= 0 {
+		opts.FailTimeout = 30 * time.Second
+	}
+	if opts.HealthCheck.Interval <= 0 {
+		opts.HealthCheck.Interval = 10 * time.Second
+	}
+	if opts.HealthCheck.Timeout <= 0 {
+		opts.HealthCheck.Timeout = 2 * time.Second
+	}
+
+	p := &ReverseProxy{
+		opts:     opts,
+		client:   &http.Client{Transport: opts.Transport, Timeout: opts.HealthCheck.Timeout},
+		balancer: builder(),
+		done:     make(chan struct{}),
+	}
+	p.proxy = &httputil.ReverseProxy{
+		Director:       p.direct,
+		Transport:      opts.Transport,
+		FlushInterval:  opts.FlushInterval,
+		ModifyResponse: opts.ModifyResponse,
+		ErrorHandler:   p.handleError,
+	}
+
+	var addrs []transport.Address
+	if opts.Resolver == nil {
+		for _, t := range opts.Targets {
+			addrs = append(addrs, transport.Address{URL: t})
+		}
+	} else {
+		var err error
+		if addrs, err = opts.Resolver.Resolve(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.update(addrs); err != nil {
+		return nil, err
+	}
+	if opts.Resolver != nil {
+		opts.Resolver.Watch(func(addrs []transport.Address) {
+			_ = p.update(addrs)
+		})
+	}
+	if opts.HealthCheck.Path != "" {
+		go p.check()
+	}
+	return p, nil
+}
+
+// Handle implements HandlerFunc.
+func (p *ReverseProxy) Handle(ctx Context) error {
+	target := p.next(ctx.Request())
+	if target == nil {
+		return NewError(http.StatusBadGateway, "no upstream available")
+	}
+
+	st := &proxyState{target: target}
+	r := ctx.Request()
+	r = r.WithContext(scontext.WithValue(r.Context(), proxyKey{}, st))
+	if p.opts.Rewrite != nil {
+		r = r.Clone(r.Context())
+		p.rewritePath(r)
+		p.opts.Rewrite(ctx, r)
+	}
+	p.proxy.ServeHTTP(ctx.Response(), r)
+
+	if st.err == nil {
+		atomic.StoreInt32(&target.fails, 0)
+		return nil
+	}
+	if ctx.Response().Committed() {
+		ctx.Logger().Errorf("web > Proxy to %s failed: %v", target.url.Host, st.err)
+		return nil
+	}
+	if e, ok := st.err.(net.Error); ok && e.Timeout() {
+		return NewError(http.StatusGatewayTimeout, st.err.Error())
+	}
+	return NewError(http.StatusBadGateway, st.err.Error())
+}
+
+// Close stops health checks and resolver watching, it is safe to call it more than once.
+func (p *ReverseProxy) Close() {
+	p.once.Do(func() {
+		close(p.done)
+		if p.opts.Resolver != nil {
+			p.opts.Resolver.Close()
+		}
+	})
+}
+
+func (p *ReverseProxy) update(addrs []transport.Address) error {
+	old := make(map[string]*upstream)
+	if targets, ok := p.targets.Load().([]*upstream); ok {
+		for _, t := range targets {
+			old[t.url.String()] = t
+		}
+	}
+
+	targets := make([]*upstream, 0, len(addrs))
+	for _, addr := range addrs {
+		if t, ok := old[addr.URL]; ok {
+			targets = append(targets, t)
+			continue
+		}
+
+		u, err := url.Parse(addr.URL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return errors.Format("web: invalid proxy target: %s", addr.URL)
+		}
+		targets = append(targets, &upstream{url: u})
+	}
+	p.targets.Store(targets)
+	return nil
+}
+
+// next selects an available upstream, if all upstreams are ejected, it selects from all of them.
+func (p *ReverseProxy) next(r *http.Request) *upstream {
+	all, _ := p.targets.Load().([]*upstream)
+	if len(all) == 0 {
+		return nil
+	}
+
+	now := time.Now().UnixNano()
+	targets := make([]*upstream, 0, len(all))
+	for _, t := range all {
+		if t.available(now) {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		targets = all
+	}
+
+	urls := make([]*url.URL, len(targets))
+	for i, t := range targets {
+		urls[i] = t.url
+	}
+	if i := p.balancer.Select(r, urls); i >= 0 && i < len(targets) {
+		return targets[i]
+	}
+	return targets[0]
+}
+
+func (p *ReverseProxy) direct(r *http.Request) {
+	st := r.Context().Value(proxyKey{}).(*proxyState)
+	if p.opts.Rewrite == nil {
+		p.rewritePath(r)
+	}
+
+	if r.Header.Get(HeaderXForwardedHost) == "" {
+		r.Header.Set(HeaderXForwardedHost, r.Host)
+	}
+	target := st.target.url
+	r.URL.Scheme = target.Scheme
+	r.URL.Host = target.Host
+	r.URL.Path, r.URL.RawPath = joinURLPath(target, r.URL)
+	if target.RawQuery != "" {
+		if r.URL.RawQuery == "" {
+			r.URL.RawQuery = target.RawQuery
+		} else {
+			r.URL.RawQuery = target.RawQuery + "&" + r.URL.RawQuery
+		}
+	}
+	if !p.opts.PreserveHost {
+		r.Host = target.Host
+	}
+
+	if r.TLS == nil {
+		r.Header.Set(HeaderXForwardedProto, "http")
+	} else {
+		r.Header.Set(HeaderXForwardedProto, "https")
+	}
+	if _, ok := r.Header["User-Agent"]; !ok {
+		// explicitly disable User-Agent so it's not set to default value
+		r.Header.Set("User-Agent", "")
+	}
+}
+
+func (p *ReverseProxy) rewritePath(r *http.Request) {
+	if prefix := p.opts.StripPrefix; prefix != "" {
+		r.URL.Path = "/" + strings.TrimLeft(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = "/" + strings.TrimLeft(strings.TrimPrefix(r.URL.RawPath, prefix), "/")
+		}
+	}
+}
+
+func (p *ReverseProxy) handleError(_ http.ResponseWriter, r *http.Request, err error) {
+	st := r.Context().Value(proxyKey{}).(*proxyState)
+	st.err = err
+	if r.Context().Err() == nil {
+		p.fail(st.target)
+	}
+}
+
+// fail records a failure of upstream, it is ejected after MaxFails consecutive failures.
+func (p *ReverseProxy) fail(t *upstream) {
+	if atomic.AddInt32(&t.fails, 1) >= int32(p.opts.MaxFails) {
+		atomic.StoreInt32(&t.fails, 0)
+		atomic.StoreInt64(&t.ejected, time.Now().Add(p.opts.FailTimeout).UnixNano())
+	}
+}
+
+func (p *ReverseProxy) check() {
+	ticker := time.NewTicker(p.opts.HealthCheck.Interval)
+	defer ticker.Stop()
+
+	for {
+		targets, _ := p.targets.Load().([]*upstream)
+		for _, t := range targets {
+			down := int32(1)
+			u := *t.url
+			u.Path, u.RawQuery = singleJoiningSlash(t.url.Path, p.opts.HealthCheck.Path), ""
+			if resp, err := p.client.Get(u.String()); err == nil {
+				resp.Body.Close()
+				if resp.StatusCode < http.StatusInternalServerError {
+					down = 0
+				}
+			}
+			atomic.StoreInt32(&t.down, down)
+		}
+
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func joinURLPath(a, b *url.URL) (path, rawpath string) {
+	if a.RawPath == "" && b.RawPath == "" {
+		return singleJoiningSlash(a.Path, b.Path), ""
+	}
+
+	apath, bpath := a.EscapedPath(), b.EscapedPath()
+	path = singleJoiningSlash(a.Path, b.Path)
+	rawpath = singleJoiningSlash(apath, bpath)
+	return
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,217 @@
+package web
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cuigh/auxo/test/assert"
+	"github.com/gorilla/websocket"
+)
+
+func TestProxy(t *testing.T) {
+	newUpstream := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Upstream", name)
+			_, _ = io.WriteString(w, r.URL.Path+"|"+r.Header.Get(HeaderXForwardedHost))
+		}))
+	}
+	u1, u2 := newUpstream("u1"), newUpstream("u2")
+	defer u1.Close()
+	defer u2.Close()
+
+	p, err := NewProxy(ProxyOptions{Targets: []string{u1.URL + "/v1", u2.URL + "/v1"}, StripPrefix: "/api"})
+	assert.NoError(t, err)
+	s := Default()
+	s.Any("/api/*", p.Handle)
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/api/users", nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "/v1/users|example.com", rec.Body.String())
+		names = append(names, rec.Header().Get("X-Upstream"))
+	}
+	assert.Equal(t, []string{"u1", "u2", "u1", "u2"}, names)
+}
+
+func TestProxy_Eject(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer up.Close()
+
+	// a closed server refuses connections
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	p, err := NewProxy(ProxyOptions{Targets: []string{down.URL, up.URL}, MaxFails: 1, FailTimeout: time.Minute})
+	assert.NoError(t, err)
+	s := Default()
+	s.Get("/", p.Handle)
+
+	codes := make([]int, 3)
+	for i := range codes {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes[i] = rec.Code
+	}
+	assert.Equal(t, []int{http.StatusBadGateway, http.StatusOK, http.StatusOK}, codes)
+}
+
+func TestProxy_HealthCheck(t *testing.T) {
+	var healthy int32
+	newUpstream := func(name string, ok func() bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/health" && !ok() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = io.WriteString(w, name)
+		}))
+	}
+	u1 := newUpstream("u1", func() bool { return atomic.LoadInt32(&healthy) == 1 })
+	u2 := newUpstream("u2", func() bool { return true })
+	defer u1.Close()
+	defer u2.Close()
+
+	opts := ProxyOptions{Targets: []string{u1.URL, u2.URL}}
+	opts.HealthCheck.Path = "/health"
+	opts.HealthCheck.Interval = 20 * time.Millisecond
+	p, err := NewProxy(opts)
+	assert.NoError(t, err)
+	defer p.Close()
+	s := Default()
+	s.Get("/", p.Handle)
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Body.String()
+	}
+	collect := func() map[string]int {
+		m := map[string]int{}
+		for i := 0; i < 4; i++ {
+			m[get()]++
+		}
+		return m
+	}
+
+	// health checks start without requests
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, map[string]int{"u2": 4}, collect())
+
+	// recovered upstream receives traffic again
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, map[string]int{"u1": 2, "u2": 2}, collect())
+}
+
+func TestProxy_WebSocket(t *testing.T) {
+	up := Default()
+	up.WebSocket("/ws", func(ctx Context, conn *Conn) error {
+		mt, b, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(mt, append([]byte("echo:"), b...))
+	})
+	us := httptest.NewServer(up)
+	defer us.Close()
+
+	s := Default()
+	p, err := NewProxy(ProxyOptions{Targets: []string{us.URL}})
+	assert.NoError(t, err)
+	s.Get("/ws", p.Handle)
+	ps := httptest.NewServer(s)
+	defer ps.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ps.URL, "http")+"/ws", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hi")))
+	_, b, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "echo:hi", string(b))
+}
+
+func TestProxy_Stream(t *testing.T) {
+	release := make(chan struct{})
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContentType, MIMETextEventStream)
+		_, _ = io.WriteString(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = io.WriteString(w, "data: 2\n\n")
+	}))
+	defer up.Close()
+	defer close(release)
+
+	s := Default()
+	p, err := NewProxy(ProxyOptions{Targets: []string{up.URL}})
+	assert.NoError(t, err)
+	s.Get("/events", p.Handle)
+	ps := httptest.NewServer(s)
+	defer ps.Close()
+
+	resp, err := http.Get(ps.URL + "/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	// the first event arrives before upstream finishes the response
+	line := make(chan string, 1)
+	go func() {
+		l, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		line <- l
+	}()
+	select {
+	case l := <-line:
+		assert.Equal(t, "data: 1\n", l)
+	case <-time.After(time.Second):
+		t.Fatal("streaming response is not flushed")
+	}
+}
+
+func TestProxy_Balancer(t *testing.T) {
+	RegisterProxyBalancer("header", func() ProxyBalancer {
+		return ProxyBalancerFunc(func(r *http.Request, targets []*url.URL) int {
+			for i, u := range targets {
+				if u.Host == r.Header.Get("X-Target") {
+					return i
+				}
+			}
+			return 0
+		})
+	})
+
+	u1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, "u1") }))
+	u2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, "u2") }))
+	defer u1.Close()
+	defer u2.Close()
+
+	_, err := NewProxy(ProxyOptions{Targets: []string{u1.URL}, Balancer: "unknown"})
+	assert.Error(t, err)
+
+	p, err := NewProxy(ProxyOptions{Targets: []string{u1.URL, u2.URL}, Balancer: "header"})
+	assert.NoError(t, err)
+	s := Default()
+	s.Get("/", p.Handle)
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Target", strings.TrimPrefix(u2.URL, "http://"))
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, "u2", rec.Body.String())
+	}
+}
@@ -0,0 +1,136 @@
+package renderer
+
+import (
+	"hash/fnv"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watcher detects changes of template files by polling their sizes and modification times,
+// it works with any fs.FS, including embedded ones which never change.
+type Watcher struct {
+	fs       fs.FS
+	dir      string
+	exts     []string
+	interval time.Duration
+
+	locker  sync.Mutex
+	checked time.Time
+	sum     uint64
+}
+
+// NewWatcher creates a Watcher for files with extensions in dir, all files are watched if exts is empty.
+// Files are scanned at most once in interval.
+func NewWatcher(fsys fs.FS, dir string, interval time.Duration, exts ...string) *Watcher {
+	w := &Watcher{fs: fsys, dir: dir, exts: exts, interval: interval}
+	w.sum = w.scan()
+	w.checked = time.Now()
+	return w
+}
+
+// Changed reports whether any file was added, removed or modified since last call.
+func (w *Watcher) Changed() bool {
+	w.locker.Lock()
+	defer w.locker.Unlock()
+
+	if time.Since(w.checked) < w.interval {
+		return false
+	}
+	w.checked = time.Now()
+
+	if sum := w.scan(); sum != w.sum {
+		w.sum = sum
+		return true
+	}
+	return false
+}
+
+func (w *Watcher) scan() uint64 {
+	h := fnv.New64a()
+	_ = fs.WalkDir(w.fs, w.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !w.match(path.Ext(p)) {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			_, _ = io.WriteString(h, p+"|"+strconv.FormatInt(fi.Size(), 10)+"|"+fi.ModTime().String()+"\n")
+		}
+		return nil
+	})
+	return h.Sum64()
+}
+
+func (w *Watcher) match(ext string) bool {
+	if len(w.exts) == 0 {
+		return true
+	}
+	for _, e := range w.exts {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// errPosition matches template name and line in errors, like `template: users/list:12:5: ...`
+// of html/template or `Jet Runtime Error ("/index.jet":3): ...` of jet.
+var errPosition = regexp.MustCompile(`"?([^\s":()]+)"?:(\d+)`)
+
+var errorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Template Error</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; }
+.line { display: block; }
+.error { background: #ffdce0; font-weight: bold; }
+</style>
+</head>
+<body>
+<h2>Template Error</h2>
+<pre>{{ .Error }}</pre>
+{{- if .Lines }}
+<h3>{{ .Name }}</h3>
+<pre>{{ range .Lines }}<span class="line{{ if .Error }} error{{ end }}">{{ printf "%4d" .No }} | {{ .Text }}</span>{{ end }}</pre>
+{{- end }}
+</body>
+</html>
+`))
+
+type errorLine struct {
+	No    int
+	Text  string
+	Error bool
+}
+
+// ErrorPage writes an HTML page which shows the template error and source lines around it,
+// source returns content of a template by name, it is only used in debug mode.
+func ErrorPage(w io.Writer, err error, source func(name string) []byte) error {
+	data := struct {
+		Error string
+		Name  string
+		Lines []errorLine
+	}{Error: err.Error()}
+
+	if m := errPosition.FindStringSubmatch(data.Error); m != nil && source != nil {
+		no, _ := strconv.Atoi(m[2])
+		if src := source(m[1]); src != nil {
+			data.Name = m[1]
+			lines := strings.Split(string(src), "\n")
+			for i := no - 5; i <= no+5; i++ {
+				if i >= 1 && i <= len(lines) {
+					data.Lines = append(data.Lines, errorLine{No: i, Text: lines[i-1], Error: i == no})
+				}
+			}
+		}
+	}
+	return errorPage.Execute(w, data)
+}
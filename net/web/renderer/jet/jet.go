@@ -1,6 +1,7 @@
 package jet
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/CloudyKit/jet/v6"
 	"github.com/cuigh/auxo/app"
@@ -10,11 +11,15 @@ import (
 	"github.com/cuigh/auxo/net/web/renderer"
 	"io"
 	"io/fs"
+	"net/http"
 	"path/filepath"
+	"strings"
 )
 
 type Renderer struct {
-	set *jet.Set
+	set    *jet.Set
+	loader jet.Loader
+	debug  bool
 }
 
 type fsLoader struct {
@@ -56,6 +61,8 @@ func (opts *Options) ensure() (err error) {
 
 type Option func(opts *Options)
 
+// Debug enables development mode of jet, templates are reloaded on every rendering, and errors are rendered
+// as a page with source lines around. Layouts are supported natively by `{{ extends }}` and `{{ block }}`.
 func Debug(b ...bool) Option {
 	return func(opts *Options) {
 		opts.debug = len(b) == 0 || b[0]
@@ -123,7 +130,7 @@ func New(opts ...Option) (*Renderer, error) {
 	for k, v := range options.vars {
 		set.AddGlobal(k, v)
 	}
-	return &Renderer{set: set}, nil
+	return &Renderer{set: set, loader: loader, debug: options.debug}, nil
 }
 
 func Must(opts ...Option) *Renderer {
@@ -136,8 +143,43 @@ func Must(opts ...Option) *Renderer {
 
 func (r *Renderer) Render(w io.Writer, name string, data interface{}, ctx web.Context) error {
 	tpl, err := r.set.GetTemplate(name)
+	if !r.debug {
+		if err == nil {
+			err = tpl.Execute(w, contextVars(ctx), data)
+		}
+		return err
+	}
+
 	if err == nil {
-		err = tpl.Execute(w, nil, data)
+		buf := &bytes.Buffer{}
+		if err = tpl.Execute(buf, contextVars(ctx), data); err == nil {
+			_, err = buf.WriteTo(w)
+			return err
+		}
 	}
-	return err
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+	return renderer.ErrorPage(w, err, r.source)
+}
+
+func (r *Renderer) source(name string) []byte {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	f, err := r.loader.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	b, _ := io.ReadAll(f)
+	return b
+}
+
+// contextVars returns variables depend on request context, use `{{ csrfField | raw }}` to output the hidden input.
+func contextVars(ctx web.Context) jet.VarMap {
+	return jet.VarMap{}.
+		Set("csrfToken", web.CSRFToken(ctx)).
+		Set("csrfField", string(web.CSRFField(ctx)))
 }
@@ -0,0 +1,29 @@
+package jet
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestDebug(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.jet": {Data: []byte(`<title>{{ yield title() }}</title>`)},
+		"index.jet":  {Data: []byte("{{ extends \"layout.jet\" }}\n{{ block title() }}Home{{ end }}")},
+		"bad.jet":    {Data: []byte("line1\n{{ .Missing.Name }}\nline3")},
+	}
+	r, err := New(Dir(fsys, ""), Debug())
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	assert.NoError(t, r.Render(rec, "index.jet", nil, nil))
+	assert.Equal(t, "<title>Home</title>", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	assert.NoError(t, r.Render(rec, "bad.jet", nil, nil))
+	assert.Equal(t, 500, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), "   2 | {{ .Missing.Name }}"), rec.Body.String())
+}
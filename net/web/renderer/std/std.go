@@ -1,24 +1,34 @@
 package std
 
 import (
+	"bytes"
 	"errors"
 	"github.com/cuigh/auxo/app"
 	"github.com/cuigh/auxo/ext/files"
 	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/net/web/renderer"
 	"html/template"
 	"io"
 	"io/fs"
-	"io/ioutil"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Options struct {
-	debug bool
-	fs    fs.FS
-	dir   string
-	exts  []string
-	fm    template.FuncMap
+	debug    bool
+	interval time.Duration
+	fs       fs.FS
+	dir      string
+	exts     []string
+	fm       template.FuncMap
 }
 
 func (opts *Options) ensure() error {
@@ -65,12 +75,20 @@ func Ext(ext ...string) Option {
 	}
 }
 
+// Debug enables recompiling templates after files change and rendering errors as a page.
 func Debug(b ...bool) Option {
 	return func(opts *Options) {
 		opts.debug = len(b) == 0 || b[0]
 	}
 }
 
+// Interval sets the interval of checking file changes in debug mode, default is 1s.
+func Interval(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.interval = d
+	}
+}
+
 func Func(name string, fn interface{}) Option {
 	return func(opts *Options) {
 		opts.fm[name] = fn
@@ -86,13 +104,70 @@ func FuncMap(m map[string]interface{}) Option {
 }
 
 type Renderer struct {
-	opts *Options
+	opts    *Options
+	watcher *renderer.Watcher
+	locker  sync.Mutex
+	state   atomic.Value // *state
+}
+
+type state struct {
+	c   *compiled
+	err error
+}
+
+// compiled holds parsed templates, pages extending layouts have their own sets,
+// so the same blocks can be defined by different pages.
+type compiled struct {
+	base    *set
+	pages   map[string]*set
+	sources map[string][]byte
+}
+
+func (c *compiled) lookup(name string) *set {
+	if s, ok := c.pages[name]; ok {
+		return s
+	}
+	return c.base
+}
+
+func (c *compiled) source(name string) []byte {
+	return c.sources[name]
+}
+
+// set is a group of templates, they are cloned and pooled for execution.
+type set struct {
 	t    *template.Template
+	pool sync.Pool
 }
 
+// instance is a clone of templates whose context functions are bound to a request.
+type instance struct {
+	t   *template.Template
+	ctx web.Context
+}
+
+// contextFuncs are functions depend on request context, placeholders are registered for parsing.
+var contextFuncs = template.FuncMap{
+	"csrfToken": func() string { return "" },
+	"csrfField": func() template.HTML { return "" },
+}
+
+// extends matches the directive which must be the first action of a page, like `{{ extends "layouts/main" }}`.
+var extends = regexp.MustCompile(`^\s*{{-?\s*extends\s+"([^"]+)"\s*-?}}`)
+
+// New creates a Renderer with html/template.
+//
+// A page can inherit a layout with `{{ extends "layouts/main" }}` as its first action, the layout declares
+// blocks with `{{ block "content" . }}default{{ end }}` and the page overrides them with `{{ define "content" }}`.
+// Layouts can extend other layouts. In debug mode, templates are recompiled after files change, and errors
+// are rendered as a page with source lines around.
 func New(opts ...Option) (r *Renderer, err error) {
 	options := &Options{
-		fm: make(template.FuncMap),
+		fm:       make(template.FuncMap),
+		interval: time.Second,
+	}
+	for k, v := range contextFuncs {
+		options.fm[k] = v
 	}
 	for _, opt := range opts {
 		opt(options)
@@ -101,14 +176,21 @@ func New(opts ...Option) (r *Renderer, err error) {
 	if err != nil {
 		return
 	}
+	if options.fs == nil {
+		options.fs, options.dir = os.DirFS(options.dir), "."
+	}
 
 	r = &Renderer{
 		opts: options,
 	}
-	err = r.compile()
-	if err != nil {
+	c, err := r.compile()
+	if err != nil && !options.debug {
 		return nil, err
 	}
+	r.state.Store(&state{c: c, err: err})
+	if options.debug {
+		r.watcher = renderer.NewWatcher(options.fs, options.dir, options.interval, options.exts...)
+	}
 	return r, nil
 }
 
@@ -121,66 +203,162 @@ func Must(opts ...Option) *Renderer {
 }
 
 func (r *Renderer) Render(w io.Writer, name string, data interface{}, ctx web.Context) (err error) {
-	return r.t.ExecuteTemplate(w, name, data)
+	if r.opts.debug {
+		return r.debugRender(w, name, data, ctx)
+	}
+
+	c := r.state.Load().(*state).c
+	return c.lookup(name).execute(w, name, data, ctx)
 }
 
-func (r *Renderer) compile() (err error) {
-	if r.opts.fs == nil {
-		err = filepath.Walk(r.opts.dir, func(p string, d os.FileInfo, e error) error {
-			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				return nil
-			}
+// debugRender recompiles templates if files changed, and renders errors as a page.
+func (r *Renderer) debugRender(w io.Writer, name string, data interface{}, ctx web.Context) error {
+	if r.watcher.Changed() {
+		r.locker.Lock()
+		c, err := r.compile()
+		r.state.Store(&state{c: c, err: err})
+		r.locker.Unlock()
+	}
 
-			ext := filepath.Ext(p)
-			if !r.match(ext) {
-				return nil
-			}
+	s := r.state.Load().(*state)
+	err := s.err
+	if err == nil {
+		buf := &bytes.Buffer{}
+		if err = s.c.lookup(name).execute(buf, name, data, ctx); err == nil {
+			_, err = buf.WriteTo(w)
+			return err
+		}
+	}
 
-			rel, err := filepath.Rel(r.opts.dir, p)
-			if err != nil {
-				return err
-			}
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+	return renderer.ErrorPage(w, err, s.c.source)
+}
 
-			data, err := ioutil.ReadFile(p)
-			if err != nil {
-				return err
-			}
+func (s *set) execute(w io.Writer, name string, data interface{}, ctx web.Context) (err error) {
+	var inst *instance
+	if v := s.pool.Get(); v != nil {
+		inst = v.(*instance)
+	} else if inst, err = s.clone(); err != nil {
+		return
+	}
+
+	inst.ctx = ctx
+	defer func() {
+		inst.ctx = nil
+		s.pool.Put(inst)
+	}()
+	return inst.t.ExecuteTemplate(w, name, data)
+}
 
-			name := filepath.ToSlash(rel[0 : len(rel)-len(ext)])
-			r.parse(name, data)
+// clone creates an instance from the original templates which are never executed.
+// Instances are pooled and reused, so templates are only escaped once for each instance.
+func (s *set) clone() (*instance, error) {
+	t, err := s.t.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	inst := &instance{t: t}
+	t.Funcs(template.FuncMap{
+		"csrfToken": func() string { return web.CSRFToken(inst.ctx) },
+		"csrfField": func() template.HTML { return web.CSRFField(inst.ctx) },
+	})
+	return inst, nil
+}
+
+// compile parses all templates, sources are always returned for error pages.
+func (r *Renderer) compile() (c *compiled, err error) {
+	c = &compiled{
+		pages:   make(map[string]*set),
+		sources: make(map[string][]byte),
+	}
+	layouts := make(map[string]string)
+	bodies := make(map[string]string)
+	err = fs.WalkDir(r.opts.fs, r.opts.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
 			return nil
-		})
-	} else {
-		err = fs.WalkDir(r.opts.fs, r.opts.dir, func(p string, d fs.DirEntry, e error) error {
-			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				return nil
-			}
+		}
 
-			ext := filepath.Ext(p)
-			if !r.match(ext) {
-				return nil
-			}
+		ext := path.Ext(p)
+		if !r.match(ext) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(r.opts.dir, p)
+		if err != nil {
+			return err
+		}
+
+		data, err := fs.ReadFile(r.opts.fs, p)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(rel[0 : len(rel)-len(ext)])
+		c.sources[name] = data
+		body := string(data)
+		if m := extends.FindStringSubmatchIndex(body); m != nil {
+			layouts[name] = body[m[2]:m[3]]
+			// keep line numbers of errors
+			body = strings.Repeat("\n", strings.Count(body[:m[1]], "\n")) + body[m[1]:]
+		}
+		bodies[name] = body
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	names := make([]string, 0, len(bodies))
+	for name := range bodies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-			rel, err := filepath.Rel(r.opts.dir, p)
-			if err != nil {
-				return err
+	// templates without layout are shared by all pages
+	base := template.New("").Funcs(r.opts.fm)
+	for _, name := range names {
+		if _, ok := layouts[name]; !ok {
+			if _, err = base.New(name).Parse(bodies[name]); err != nil {
+				return
 			}
+		}
+	}
+	c.base = &set{t: base}
+
+	for _, name := range names {
+		if _, ok := layouts[name]; !ok {
+			continue
+		}
 
-			data, err := fs.ReadFile(r.opts.fs, p)
-			if err != nil {
-				return err
+		var t *template.Template
+		if t, err = base.Clone(); err != nil {
+			return
+		}
+		chain := []string{name}
+		for layout, ok := layouts[name]; ok; layout, ok = layouts[layout] {
+			if _, exist := bodies[layout]; !exist {
+				return c, errors.New("std: layout of " + chain[len(chain)-1] + " not found: " + layout)
+			}
+			if len(chain) > len(layouts) {
+				return c, errors.New("std: circular layout inheritance: " + name)
 			}
+			chain = append(chain, layout)
+		}
 
-			name := filepath.ToSlash(rel[0 : len(rel)-len(ext)])
-			r.parse(name, data)
-			return nil
-		})
+		// parse from root layout to page, blocks of layouts are overridden by descendants
+		tpl := t.New(name)
+		for i := len(chain) - 1; i >= 0; i-- {
+			if _, err = tpl.Parse(bodies[chain[i]]); err != nil {
+				return
+			}
+		}
+		c.pages[name] = &set{t: t}
 	}
 	return
 }
@@ -193,15 +371,3 @@ func (r *Renderer) match(ext string) bool {
 	}
 	return false
 }
-
-func (r *Renderer) parse(name string, data []byte) {
-	var tpl *template.Template
-	if r.t == nil {
-		r.t = template.New(name)
-		tpl = r.t
-	} else {
-		tpl = r.t.New(name)
-	}
-	tpl.Funcs(r.opts.fm)
-	template.Must(tpl.Parse(string(data)))
-}
@@ -0,0 +1,69 @@
+package std
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestExtends(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":  {Data: []byte(`<title>{{ block "title" . }}Site{{ end }}</title>{{ block "content" . }}{{ end }}`)},
+		"layouts/admin.html": {Data: []byte(`{{ extends "layouts/base" }}{{ define "content" }}<nav/>{{ block "main" . }}{{ end }}{{ end }}`)},
+		"home.html":          {Data: []byte(`{{ extends "layouts/base" }}{{ define "content" }}home {{ . }}{{ end }}`)},
+		"users.html":         {Data: []byte(`{{ extends "layouts/admin" }}{{ define "title" }}Users{{ end }}{{ define "main" }}{{ template "partial" }}{{ end }}`)},
+		"partial.html":       {Data: []byte(`list`)},
+	}
+	r, err := New(Dir(fsys, "."))
+	assert.NoError(t, err)
+
+	cases := []struct {
+		Name     string
+		Expected string
+	}{
+		{"home", "<title>Site</title>home x"},
+		{"users", "<title>Users</title><nav/>list"},
+		{"partial", "list"},
+	}
+	for _, c := range cases {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, r.Render(buf, c.Name, "x", nil))
+		assert.Equal(t, c.Expected, buf.String())
+	}
+
+	fsys["page.html"] = &fstest.MapFile{Data: []byte(`{{ extends "missing" }}`)}
+	_, err = New(Dir(fsys, "."))
+	assert.Error(t, err)
+}
+
+func TestDebug(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.html")
+	assert.NoError(t, os.WriteFile(file, []byte("v1"), 0644))
+
+	r, err := New(Dir(os.DirFS(dir), "."), Debug(), Interval(0))
+	assert.NoError(t, err)
+	buf := &bytes.Buffer{}
+	assert.NoError(t, r.Render(buf, "index", nil, nil))
+	assert.Equal(t, "v1", buf.String())
+
+	// modification time may not change in the same tick, so size is changed too
+	assert.NoError(t, os.WriteFile(file, []byte("line1\n{{ .Missing }\nline3"), 0644))
+	_ = os.Chtimes(file, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	rec := httptest.NewRecorder()
+	assert.NoError(t, r.Render(rec, "index", nil, nil))
+	assert.Equal(t, 500, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), `   2 | {{ .Missing }`))
+
+	assert.NoError(t, os.WriteFile(file, []byte("version2"), 0644))
+	buf.Reset()
+	assert.NoError(t, r.Render(buf, "index", nil, nil))
+	assert.Equal(t, "version2", buf.String())
+}
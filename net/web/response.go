@@ -19,6 +19,10 @@ type ResponseWriter interface {
 	Committed() bool
 	Size() int
 	Status() int
+	// Before registers a function which is called just before the header is committed.
+	Before(fn func())
+	// Unwrap returns the underlying http.ResponseWriter.
+	Unwrap() http.ResponseWriter
 }
 
 type responseWriter struct {
@@ -27,6 +31,7 @@ type responseWriter struct {
 	size      int
 	committed bool
 	server    *Server
+	before    []func()
 }
 
 func newResponse(w http.ResponseWriter, s *Server) (r *responseWriter) {
@@ -47,6 +52,7 @@ func (r *responseWriter) WriteHeader(code int) {
 		return
 	}
 	r.status = code
+	r.runBefore()
 	r.Header().Set(HeaderServer, r.server.cfg.Name)
 	r.ResponseWriter.WriteHeader(code)
 	r.committed = true
@@ -54,6 +60,7 @@ func (r *responseWriter) WriteHeader(code int) {
 
 func (r *responseWriter) CommitHeader() {
 	if !r.committed {
+		r.runBefore()
 		r.Header().Set(HeaderServer, r.server.cfg.Name)
 		r.ResponseWriter.WriteHeader(r.status)
 		r.committed = true
@@ -82,6 +89,20 @@ func (r *responseWriter) Status() int {
 	return r.status
 }
 
+// Before registers a function which is called just before the header is committed,
+// it is useful for setting headers or cookies which depend on the handling result.
+func (r *responseWriter) Before(fn func()) {
+	r.before = append(r.before, fn)
+}
+
+func (r *responseWriter) runBefore() {
+	fns := r.before
+	r.before = nil
+	for _, fn := range fns {
+		fn()
+	}
+}
+
 // Committed returns if the header was send to response.
 func (r *responseWriter) Committed() bool {
 	return r.committed
@@ -99,7 +120,11 @@ func (r *responseWriter) Flush() {
 // take over the connection.
 func (r *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if h, ok := r.ResponseWriter.(http.Hijacker); ok {
-		return h.Hijack()
+		conn, rw, err := h.Hijack()
+		if err == nil {
+			r.committed = true
+		}
+		return conn, rw, err
 	}
 	return nil, nil, http.ErrNotSupported
 }
@@ -111,9 +136,15 @@ func (r *responseWriter) Push(target string, opts *http.PushOptions) error {
 	return http.ErrNotSupported
 }
 
+// Unwrap returns the original http.ResponseWriter, it is used by http.ResponseController.
+func (r *responseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func (r *responseWriter) reset(w http.ResponseWriter) {
 	r.ResponseWriter = w
 	r.size = 0
 	r.status = http.StatusOK
 	r.committed = false
+	r.before = nil
 }
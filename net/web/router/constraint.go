@@ -0,0 +1,114 @@
+package router
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cuigh/auxo/errors"
+)
+
+// Constraint reports whether a path parameter value is acceptable.
+type Constraint func(value string) bool
+
+var constraints = map[string]Constraint{
+	"int":   isInt,
+	"uint":  isUint,
+	"alpha": isAlpha,
+	"alnum": isAlnum,
+	"uuid":  isUUID,
+}
+
+// RegisterConstraint registers a named constraint, which can be used in routes like `/users/{id:name}`.
+// It is not concurrency safe, so call it before adding routes.
+func RegisterConstraint(name string, c Constraint) {
+	constraints[name] = c
+}
+
+// parseParam parses parameter segment like `:id`, `{id}` or `{id:int}`.
+func parseParam(text string) (name, expr string) {
+	if text[0] == ':' {
+		return text[1:], ""
+	}
+
+	text = text[1 : len(text)-1]
+	if i := strings.IndexByte(text, ':'); i >= 0 {
+		return text[:i], text[i+1:]
+	}
+	return text, ""
+}
+
+// compileConstraint returns a registered constraint, or compiles expr as a regular expression which must match the whole value.
+func compileConstraint(expr string) (Constraint, error) {
+	if c, ok := constraints[expr]; ok {
+		return c, nil
+	}
+
+	r, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, errors.Format("invalid parameter constraint '%s': %v", expr, err)
+	}
+	return r.MatchString, nil
+}
+
+func isInt(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return isUint(s)
+}
+
+func isUint(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c|0x20 < 'a' || c|0x20 > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if (c < '0' || c > '9') && (c|0x20 < 'a' || c|0x20 > 'f') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -146,6 +146,8 @@ type node struct {
 	params []string
 	routes *routeMap
 	parent *node
+	expr   string     // constraint expression of param node
+	match  Constraint // nil if param node is unconstrained
 	children
 }
 
@@ -161,30 +163,39 @@ func newNode(kind nodeKind, text string, parent *node) *node {
 		n.path = parent.path + text
 		n.params = parent.params
 	}
-	if kind != kindStatic {
-		n.params = append(n.params, text[1:])
+	switch kind {
+	case kindParam:
+		name, expr := parseParam(text)
+		n.params = append(n.params[:len(n.params):len(n.params)], name)
+		n.expr = expr
+	case kindAny:
+		n.params = append(n.params[:len(n.params):len(n.params)], text[1:])
 	}
 	return n
 }
 
-func (n *node) find(method, path string, paramValues []string, paramIndex int) (r *route, tsr bool) {
+func (n *node) name() string {
+	return n.params[len(n.params)-1]
+}
+
+func (n *node) find(method, path string, paramValues []string, paramIndex int, opts *Options) (r *route, tsr bool) {
 	var tsr1, tsr2 bool
 
 	// 1. search static nodes
 	for _, c := range n.static {
-		if c.text[0] != path[0] {
+		if c.text[0] != opts.char(path[0]) {
 			continue
 		}
 
 		i, ln, lp := 0, len(c.text), len(path)
 		for ; i < ln && i < lp; i++ {
-			if c.text[i] != path[i] {
+			if c.text[i] != opts.char(path[i]) {
 				goto WILD
 			}
 		}
 
 		if lp > ln {
-			r, tsr1 = c.find(method, path[len(c.text):], paramValues, paramIndex)
+			r, tsr1 = c.find(method, path[len(c.text):], paramValues, paramIndex, opts)
 		} else if lp == ln {
 			r = c.getRoute(method)
 			if r == nil && c.any != nil {
@@ -201,29 +212,51 @@ func (n *node) find(method, path string, paramValues []string, paramIndex int) (
 	}
 
 WILD:
-	// 2. check param node
-	if n.param != nil {
+	// 2. check param nodes, constrained nodes take precedence over unconstrained one
+	if len(n.param) > 0 {
 		i := strings.IndexByte(path, '/')
+		value := path
 		if i > 0 {
-			paramValues[paramIndex] = path[:i]
-			r, tsr2 = n.param.find(method, path[i:], paramValues, paramIndex+1)
-		} else {
-			paramValues[paramIndex] = path
-			r = n.param.getRoute(method)
-			if r == nil || r.handler == nil {
-				tsr2 = n.param.getStatic("/") != nil
-			}
+			value = path[:i]
 		}
 
-		if r != nil && r.handler != nil {
-			return r, false
+		if value, ok := opts.decode(value); ok {
+			for _, p := range n.param {
+				if p.match != nil && !p.match(value) {
+					continue
+				}
+
+				var (
+					pr   *route
+					ptsr bool
+				)
+				paramValues[paramIndex] = value
+				if i > 0 {
+					pr, ptsr = p.find(method, path[i:], paramValues, paramIndex+1, opts)
+				} else {
+					pr = p.getRoute(method)
+					if pr == nil || pr.handler == nil {
+						ptsr = p.getStatic("/") != nil
+					}
+				}
+
+				if pr != nil {
+					if pr.handler != nil {
+						return pr, false
+					}
+					r = pr
+				}
+				tsr2 = tsr2 || ptsr
+			}
 		}
 	}
 
 	// 3. check any node
 	if n.any != nil {
-		paramValues[paramIndex] = path
-		return n.any.getRoute(method), false
+		if value, ok := opts.decode(path); ok {
+			paramValues[paramIndex] = value
+			return n.any.getRoute(method), false
+		}
 	}
 
 	return r, tsr1 || tsr2 || (path == "/" && n.routes != nil)
@@ -257,18 +290,22 @@ func (n *node) correctPath() {
 	}
 }
 
-func (n *node) add(path string) (*node, error) {
+func (n *node) add(path string, opts *Options) (*node, error) {
 	var (
 		err         error
 		c           = n
 		start, i, l = 0, 0, len(path)
 	)
 	for ; i < l; i++ {
-		if path[i] == ':' {
-			c = c.addSegment(path[start:i])
+		if path[i] == ':' || path[i] == '{' {
+			c = c.addSegment(opts.static(path[start:i]))
 			start = i
 
-			for ; i < l && path[i] != '/'; i++ {
+			if path[i] == ':' {
+				for ; i < l && path[i] != '/'; i++ {
+				}
+			} else if i, err = closeBrace(path, i); err != nil {
+				return nil, err
 			}
 			if i == l {
 				return c.children.setParam(c, path[start:i])
@@ -279,12 +316,32 @@ func (n *node) add(path string) (*node, error) {
 			}
 			start = i
 		} else if path[i] == '*' {
-			c = c.addSegment(path[start:i])
+			c = c.addSegment(opts.static(path[start:i]))
 			return c.setAny(c, path[i:])
 		}
 	}
 
-	return c.addSegment(path[start:]), nil
+	return c.addSegment(opts.static(path[start:])), nil
+}
+
+// closeBrace returns the index after the brace which closes the one at index i,
+// braces in constraint expression like `{code:[0-9]{3}}` are balanced.
+func closeBrace(path string, i int) (int, error) {
+	depth := 0
+	for j := i; j < len(path); j++ {
+		switch path[j] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				if j+1 == len(path) || path[j+1] == '/' {
+					return j + 1, nil
+				}
+				return 0, errors.Format("invalid parameter in path: %s", path)
+			}
+		}
+	}
+	return 0, errors.Format("invalid parameter in path: %s", path)
 }
 
 func (n *node) addSegment(path string) *node {
@@ -343,8 +400,8 @@ func (n *node) walk(all bool, fn func(n *node)) {
 	for _, t := range n.static {
 		t.walk(all, fn)
 	}
-	if n.param != nil {
-		n.param.walk(all, fn)
+	for _, p := range n.param {
+		p.walk(all, fn)
 	}
 	if n.any != nil {
 		n.any.walk(all, fn)
@@ -353,7 +410,7 @@ func (n *node) walk(all bool, fn func(n *node)) {
 
 type children struct {
 	static []*node
-	param  *node
+	param  []*node // constrained nodes are ahead of the unconstrained one
 	any    *node
 }
 
@@ -361,8 +418,8 @@ func (c *children) setParent(p *node) {
 	for _, n := range c.static {
 		n.parent = p
 	}
-	if c.param != nil {
-		c.param.parent = p
+	for _, n := range c.param {
+		n.parent = p
 	}
 	if c.any != nil {
 		c.any.parent = p
@@ -379,15 +436,38 @@ func (c *children) getStatic(text string) *node {
 }
 
 func (c *children) setParam(parent *node, text string) (*node, error) {
-	if c.param != nil {
-		if c.param.text != text {
-			return nil, errors.Format("route conflict: %s <=> %s", c.param.path, c.param.parent.path+text)
+	name, expr := parseParam(text)
+	if name == "" {
+		return nil, errors.Format("missing parameter name: %s", parent.path+text)
+	}
+
+	for _, p := range c.param {
+		if p.expr == expr {
+			if p.name() != name {
+				return nil, errors.Format("route conflict: %s <=> %s", p.path, parent.path+text)
+			}
+			return p, nil
 		}
-		return c.param, nil
 	}
 
-	c.param = newNode(kindParam, text, parent)
-	return c.param, nil
+	n := newNode(kindParam, text, parent)
+	if expr == "" {
+		c.param = append(c.param, n)
+		return n, nil
+	}
+
+	var err error
+	if n.match, err = compileConstraint(expr); err != nil {
+		return nil, err
+	}
+	i := len(c.param)
+	if i > 0 && c.param[i-1].match == nil {
+		i--
+	}
+	c.param = append(c.param, nil)
+	copy(c.param[i+1:], c.param[i:])
+	c.param[i] = n
+	return n, nil
 }
 
 func (c *children) setAny(parent *node, text string) (*node, error) {
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/cuigh/auxo/errors"
 )
@@ -17,9 +19,36 @@ type Tree struct {
 
 // Options represents route tree options.
 type Options struct {
-	// todo:
-	//IgnoreCase bool
-	//DecodeParam bool
+	// IgnoreCase makes static segments of path case-insensitive, they are registered in lower case.
+	// Parameter values are kept as they are.
+	IgnoreCase bool
+
+	// DecodeParam unescapes parameter values before constraints are checked.
+	// A path with invalid escapes doesn't match any parameter.
+	DecodeParam bool
+}
+
+// char folds byte to lower case if IgnoreCase is set.
+func (opts *Options) char(c byte) byte {
+	if opts.IgnoreCase && 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func (opts *Options) static(s string) string {
+	if opts.IgnoreCase {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+func (opts *Options) decode(s string) (string, bool) {
+	if !opts.DecodeParam || strings.IndexByte(s, '%') < 0 {
+		return s, true
+	}
+	s, err := url.PathUnescape(s)
+	return s, err == nil
 }
 
 type Route interface {
@@ -44,12 +73,15 @@ func (t *Tree) MaxParam() int {
 }
 
 // Add register a route with specific methods to the tree.
+// Parameters can be declared as `:name` or `{name}`, and constrained as `{name:constraint}`,
+// constraint is a registered name(int, uint, alpha, alnum, uuid) or a regular expression, like `{slug:[a-z-]+}`.
+// A segment is matched in order of static, constrained parameters, unconstrained parameter and wildcard.
 func (t *Tree) Add(method, path string, handler interface{}) (Route, error) {
 	if path[0] != '/' {
 		return nil, errors.New("path must start with '/'")
 	}
 
-	n, err := t.root.add(path)
+	n, err := t.root.add(path, &t.opts)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +98,7 @@ func (t *Tree) Add(method, path string, handler interface{}) (Route, error) {
 // Find tries to find a matched route in the tree.
 func (t *Tree) Find(method, path string, paramValues []string) (r Route, tsr bool) {
 	var route *route
-	route, tsr = t.root.find(method, path, paramValues, 0)
+	route, tsr = t.root.find(method, path, paramValues, 0, &t.opts)
 	if route != nil {
 		r = route
 	}
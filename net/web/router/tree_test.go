@@ -467,3 +467,68 @@ func buildGithubRoute(t testing.TB) *router.Tree {
 	}
 	return tree
 }
+
+func TestTree_Constraint(t *testing.T) {
+	tree := router.New(router.Options{})
+	routes := []string{
+		"/users/me",
+		"/users/{id:int}",
+		"/users/{id:uuid}/edit",
+		"/users/{name}",
+		"/posts/{slug:[a-z-]+}",
+		"/codes/{code:[0-9]{3}}",
+	}
+	for _, r := range routes {
+		_, err := tree.Add(http.MethodGet, r, placeholder)
+		assert.NoError(t, err)
+	}
+
+	_, err := tree.Add(http.MethodGet, "/users/{uid:int}/detail", placeholder)
+	assert.Error(t, err)
+	_, err = tree.Add(http.MethodGet, "/users/{id:[}", placeholder)
+	assert.Error(t, err)
+	_, err = tree.Add(http.MethodGet, "/users/{id:int}x", placeholder)
+	assert.Error(t, err)
+
+	cases := []struct {
+		URL   string
+		Route string
+		Value string
+	}{
+		{"/users/me", "/users/me", ""},
+		{"/users/123", "/users/{id:int}", "123"},
+		{"/users/bob", "/users/{name}", "bob"},
+		{"/users/5f0b6a4e-8a8d-4b6e-9a6b-4a4f7b9d2c11/edit", "/users/{id:uuid}/edit", "5f0b6a4e-8a8d-4b6e-9a6b-4a4f7b9d2c11"},
+		{"/posts/hello-world", "/posts/{slug:[a-z-]+}", "hello-world"},
+		{"/posts/Hello", "", ""},
+		{"/codes/404", "/codes/{code:[0-9]{3}}", "404"},
+		{"/codes/4040", "", ""},
+	}
+	values := make([]string, tree.MaxParam())
+	for _, c := range cases {
+		r, _ := tree.Find(http.MethodGet, c.URL, values)
+		if c.Route == "" {
+			assert.Nil(t, r, "url: %s", c.URL)
+			continue
+		}
+		assert.NotNil(t, r, "url: %s", c.URL)
+		assert.Equal(t, c.Route, r.Path())
+		if c.Value != "" {
+			assert.Equal(t, c.Value, values[0])
+		}
+	}
+}
+
+func TestTree_Options(t *testing.T) {
+	tree := router.New(router.Options{IgnoreCase: true, DecodeParam: true})
+	_, err := tree.Add(http.MethodGet, "/Users/{name}/Files/*path", placeholder)
+	assert.NoError(t, err)
+
+	values := make([]string, tree.MaxParam())
+	r, _ := tree.Find(http.MethodGet, "/USERS/J%C3%B6rg/files/a%20b", values)
+	assert.NotNil(t, r)
+	assert.Equal(t, []string{"Jörg", "a b"}, values)
+
+	r, _ = tree.Find(http.MethodGet, "/users/a%zz/files/x", values)
+	assert.Nil(t, r)
+}
@@ -13,6 +13,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cuigh/auxo/config"
@@ -31,14 +32,21 @@ type Server struct {
 	Binder       Binder
 	Validator    Validator
 	Renderer     Renderer
+	Encoders     *Encoders
 	Logger       log.Logger
 	stdLogger    *slog.Logger
 	cfg          *Options
 	filters      []Filter
 	router       *router.Tree
-	routes       map[string]router.Route
+	hosts        []*vhost
+	routes       map[string]*namedRoute
+	trusted      *IPSet
 	ctxPool      *contextPool
 	servers      []*http.Server
+	connLocker   sync.Mutex
+	conns        map[*Conn]struct{}
+	connClosed   bool // set by closeConns, connections upgraded later are closed at once
+	closing      int32
 }
 
 // Default creates an instance of Server with default options.
@@ -64,17 +72,28 @@ func Auto() (server *Server) {
 func New(c *Options) (s *Server) {
 	c.ensure()
 	s = &Server{
-		cfg:    c,
-		Logger: log.Get(PkgName),
-		Binder: new(binder),
-		router: router.New(router.Options{}),
-		routes: make(map[string]router.Route),
+		cfg:      c,
+		Logger:   log.Get(PkgName),
+		Binder:   new(binder),
+		Encoders: NewEncoders(),
+		router:   router.New(router.Options{IgnoreCase: c.IgnoreCase, DecodeParam: c.DecodeParam}),
+		routes:   make(map[string]*namedRoute),
 	}
 	s.stdLogger = slog.New(s.Logger, "web > ", 0)
 	s.ctxPool = newContextPool(s)
+
+	var err error
+	if s.trusted, err = NewIPSet(c.TrustedProxies...); err != nil {
+		panic(err)
+	}
 	return s
 }
 
+// TrustedProxies returns the set of trusted proxies, it can be reloaded with `IPSet.Reset`.
+func (s *Server) TrustedProxies() *IPSet {
+	return s.trusted
+}
+
 // Router returns router.
 func (s *Server) Router() *router.Tree {
 	return s.router
@@ -92,6 +111,12 @@ func (s *Server) UseFunc(filters ...FilterFunc) {
 	}
 }
 
+// UseFirst adds global filters which are executed before filters added by Use, so they work
+// regardless of the order of setup, e.g. filters installed by test harnesses.
+func (s *Server) UseFirst(filters ...Filter) {
+	s.filters = append(append([]Filter(nil), filters...), s.filters...)
+}
+
 // Connect registers a route that matches 'CONNECT' method.
 func (s *Server) Connect(path string, h HandlerFunc, opts ...HandlerOption) {
 	s.register(http.MethodConnect, path, h, opts...)
@@ -145,12 +170,16 @@ func (s *Server) Any(path string, handler HandlerFunc, opts ...HandlerOption) {
 // Match registers a route that matches specific methods.
 func (s *Server) Match(methods []string, path string, handler HandlerFunc, opts ...HandlerOption) {
 	info := newHandlerInfo(handler, opts)
-	s.registerInfo(path, info, methods...)
+	s.registerInfo(nil, path, info, methods...)
 }
 
 // Handle registers routes from controller.
 // It panics if controller's Kind is not struct.
 func (s *Server) Handle(path string, controller interface{}, filters ...Filter) {
+	s.handle(nil, path, controller, filters...)
+}
+
+func (s *Server) handle(host *vhost, path string, controller interface{}, filters ...Filter) {
 	//t := struct {
 	//	Login  HandlerFunc `method:"get,post" path:"/login"`
 	//	Logout HandlerFunc `path:"/logout"`
@@ -174,11 +203,11 @@ func (s *Server) Handle(path string, controller interface{}, filters ...Filter)
 			panic(fmt.Sprintf("web > handler %s.%s isn't initialized", t.Name(), sf.Name))
 		}
 
-		s.handleField(path, t, &sf, h, filters...)
+		s.handleField(host, path, t, &sf, h, filters...)
 	}
 }
 
-func (s *Server) handleField(prefix string, t reflect.Type, sf *reflect.StructField, handler HandlerFunc, filters ...Filter) {
+func (s *Server) handleField(host *vhost, prefix string, t reflect.Type, sf *reflect.StructField, handler HandlerFunc, filters ...Filter) {
 	var (
 		p       string
 		methods []string
@@ -214,44 +243,61 @@ func (s *Server) handleField(prefix string, t reflect.Type, sf *reflect.StructFi
 	}
 
 	if methods == nil {
-		s.registerInfo(prefix+p, info, http.MethodGet)
+		s.registerInfo(host, prefix+p, info, http.MethodGet)
 	} else {
-		s.registerInfo(prefix+p, info, methods...)
+		s.registerInfo(host, prefix+p, info, methods...)
 	}
 }
 
 // Static serves static files from a custom file system.
 func (s *Server) Static(prefix string, sys http.FileSystem, fallback string, filters ...Filter) {
+	s.static(nil, prefix, sys, fallback, filters...)
+}
+
+func (s *Server) static(host *vhost, prefix string, sys http.FileSystem, fallback string, filters ...Filter) {
 	p := path.Join(prefix, "/*")
 	handler := WrapFileSystem(sys, fallback)
-	s.registerInfo(p, newHandlerInfo(handler, nil, filters...), http.MethodGet)
+	if s.cfg.Precompressed {
+		handler = precompressed(sys, handler)
+	}
+	s.registerInfo(host, p, newHandlerInfo(handler, nil, filters...), http.MethodGet)
 }
 
 // File registers a route in order to server a single file of filesystem.
 func (s *Server) File(path string, fs http.FileSystem, name string, filters ...Filter) {
 	handler := WrapFile(fs, name)
-	s.registerInfo(path, newHandlerInfo(handler, nil, filters...), http.MethodGet)
+	s.registerInfo(nil, path, newHandlerInfo(handler, nil, filters...), http.MethodGet)
 }
 
 func (s *Server) register(method, path string, handler HandlerFunc, opts ...HandlerOption) {
 	info := newHandlerInfo(handler, opts)
-	s.registerInfo(path, info, method)
+	s.registerInfo(nil, path, info, method)
 }
 
-func (s *Server) registerInfo(path string, info *handlerInfo, methods ...string) {
+func (s *Server) registerInfo(host *vhost, path string, info *handlerInfo, methods ...string) {
+	tree := s.router
+	if host != nil {
+		tree = host.router
+	}
+
 	for _, m := range methods {
-		r, err := s.router.Add(m, path, info)
+		r, err := tree.Add(m, path, info)
 		if err != nil {
 			panic(err)
 		}
 
 		if info.name == "" {
-			info.name = m + ":" + path
+			// names are qualified with host to avoid conflicts, like `GET:api.example.com[Accept-Version=2]/users`
+			if host != nil {
+				info.name = m + ":" + host.String() + path
+			} else {
+				info.name = m + ":" + path
+			}
 		}
 		if _, ok := s.routes[info.name]; ok {
 			s.Logger.Warnf("web > A handler with name '%s' already exists", info.name)
 		} else {
-			s.routes[info.name] = r
+			s.routes[info.name] = &namedRoute{Route: r, host: host}
 		}
 	}
 }
@@ -264,9 +310,13 @@ func (s *Server) Group(prefix string, filters ...Filter) (g *Group) {
 }
 
 // URL generates an URL from handler name and provided parameters.
+// URLs of routes registered on a virtual host with exact host name are qualified like `//api.example.com/users/1`.
 func (s *Server) URL(name string, params ...interface{}) string {
 	if r := s.routes[name]; r != nil {
-		return r.URL(params)
+		if r.host.qualified() {
+			return "//" + r.host.host + r.URL(params...)
+		}
+		return r.URL(params...)
 	}
 	return ""
 }
@@ -278,6 +328,27 @@ func (s *Server) Handler(name string) (handler HandlerInfo) {
 	return
 }
 
+// Walk traverses all registered routes, host is the pattern of virtual host with header matchers,
+// e.g. `api.example.com[Accept-Version=2]`, it is empty for routes of the default host.
+func (s *Server) Walk(fn func(host, method, path string, handler HandlerInfo)) {
+	for _, h := range s.hosts {
+		host := h.String()
+		h.router.Walk(func(r router.Route, method string) {
+			fn(host, method, r.Path(), r.Handler().(HandlerInfo))
+		})
+	}
+	s.router.Walk(func(r router.Route, method string) {
+		fn("", method, r.Path(), r.Handler().(HandlerInfo))
+	})
+}
+
+func (s *Server) walk(fn func(r router.Route, method string)) {
+	for _, h := range s.hosts {
+		h.router.Walk(fn)
+	}
+	s.router.Walk(fn)
+}
+
 // AcquireContext returns an `Context` instance from the pool.
 // You must return the context by calling `ReleaseContext()`.
 func (s *Server) AcquireContext(w http.ResponseWriter, r *http.Request) Context {
@@ -294,13 +365,19 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	c := s.ctxPool.Get(w, r)
 
 	p := r.URL.EscapedPath()
-	route, tsr := s.router.Find(r.Method, p, c.pathValues)
+	route, tsr := s.find(r, p, c.pathValues)
 	if tsr && s.cfg.RedirectTrailingSlash {
 		s.redirect(c, p)
 	} else {
 		s.execute(c, route)
 	}
+	s.release(c)
+}
 
+func (s *Server) release(c *context) {
+	if c.stream != nil {
+		c.stream.Close()
+	}
 	s.ctxPool.Put(c)
 }
 
@@ -332,6 +409,9 @@ func (s *Server) execute(c *context, route router.Route) {
 
 	// attach filters
 	h := c.Handler().Action()
+	if t := c.Handler().Timeout(); t > 0 {
+		h = TimeoutHandler(h, t)
+	}
 	for i := len(s.filters) - 1; i >= 0; i-- {
 		h = s.filters[i].Apply(h)
 	}
@@ -457,14 +537,23 @@ func (s *Server) getTLSConfig(entry *Entry) (tlsConfig *tls.Config, err error) {
 }
 
 func (s *Server) printRoutes() {
-	s.router.Walk(func(r router.Route, m string) {
+	s.walk(func(r router.Route, m string) {
 		handler := r.Handler().(*handlerInfo)
 		s.Logger.Debugf("web > [%s] %s -> %s", texts.PadCenter(m, ' ', 7), r.Path(), handler.Name())
 	})
 }
 
 // Close gracefully shutdown the internal HTTP servers with timeout.
+// Readiness checks fail at once, but requests are still served for Options.ShutdownDelay before listeners
+// are closed, so load balancers can drain traffic. Active WebSocket connections are closed with a going away message.
 func (s *Server) Close(timeout time.Duration) {
+	atomic.StoreInt32(&s.closing, 1)
+	if d := s.cfg.ShutdownDelay; d > 0 && len(s.servers) > 0 {
+		// readiness checks fail from now on, keep serving until load balancers stop sending traffic
+		s.Logger.Infof("web > Waiting %s for load balancers to drain traffic", d)
+		time.Sleep(d)
+	}
+	s.closeConns()
 	if timeout <= 0 {
 		for _, server := range s.servers {
 			if err := server.Close(); err != nil {
@@ -493,6 +582,19 @@ func (s *Server) Close(timeout time.Duration) {
 	}
 }
 
+// DelayShutdown sets the default value of Options.ShutdownDelay, a configured value is kept.
+// Health endpoints call it, so load balancers can see failed readiness before listeners are closed.
+func (s *Server) DelayShutdown(d time.Duration) {
+	if s.cfg.ShutdownDelay == 0 {
+		s.cfg.ShutdownDelay = d
+	}
+}
+
+// Closing reports whether Close has been called, health checks use it to stop accepting traffic from load balancers.
+func (s *Server) Closing() bool {
+	return atomic.LoadInt32(&s.closing) == 1
+}
+
 // tcpKeepAliveListener sets TCP keep-alive timeouts on accepted
 // connections. It's used by ListenAndServe and ListenAndServeTLS so
 // dead TCP connections (e.g. closing laptop mid-download) eventually
@@ -4,6 +4,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/cuigh/auxo/test/assert"
@@ -23,3 +27,51 @@ func TestServer(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, text, string(bytes))
 }
+
+func TestServer_Precompressed(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "static"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "static", "app.js"), []byte("plain"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "static", "app.js.gz"), []byte("gzipped"), 0644))
+
+	s := New(&Options{Precompressed: true})
+	s.Static("/static", http.Dir(dir), "")
+
+	for accept, expected := range map[string]string{"": "plain", "gzip": "gzipped", "br": "plain"} {
+		req := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		req.Header.Set(HeaderAcceptEncoding, accept)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, rec.Body.String())
+		assert.True(t, strings.HasPrefix(rec.Header().Get(HeaderContentType), "text/javascript"))
+	}
+}
+
+func TestServer_PathConstraint(t *testing.T) {
+	s := New(&Options{IgnoreCase: true})
+	s.Get("/users/me", func(ctx Context) error { return ctx.Text("me") })
+	s.Get("/users/{id:int}", func(ctx Context) error {
+		id, err := ctx.PathInt("id")
+		if err != nil {
+			return err
+		}
+		return ctx.Text(strconv.Itoa(id * 2))
+	})
+	s.Get("/users/{name}", func(ctx Context) error {
+		_, err := ctx.PathInt("name")
+		return err
+	})
+
+	for url, expected := range map[string]string{"/users/me": "me", "/Users/ME": "me", "/users/21": "42"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/bob", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"github.com/cuigh/auxo/errors"
+)
+
+// ErrInvalidCookie is returned when a cookie value is tampered or can't be decoded.
+var ErrInvalidCookie = errors.New("session: invalid cookie")
+
+// Codec encodes values of session cookies.
+type Codec interface {
+	Encode(value []byte) (string, error)
+	Decode(value string) ([]byte, error)
+}
+
+type signer struct {
+	keys [][]byte
+}
+
+// NewSigner returns a Codec which signs values with HMAC-SHA256, values are still readable by clients.
+// The first key is used to sign, and all keys are used to verify, so keys can be rotated smoothly.
+func NewSigner(keys ...[]byte) Codec {
+	if len(keys) == 0 {
+		panic("session: signer requires at least one key")
+	}
+	return &signer{keys: keys}
+}
+
+func (s *signer) Encode(value []byte) (string, error) {
+	v := base64.RawURLEncoding.EncodeToString(value)
+	return v + "." + base64.RawURLEncoding.EncodeToString(s.sign(s.keys[0], v)), nil
+}
+
+func (s *signer) Decode(value string) ([]byte, error) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return nil, ErrInvalidCookie
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	for _, key := range s.keys {
+		if hmac.Equal(mac, s.sign(key, value[:i])) {
+			return base64.RawURLEncoding.DecodeString(value[:i])
+		}
+	}
+	return nil, ErrInvalidCookie
+}
+
+func (s *signer) sign(key []byte, value string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(value))
+	return h.Sum(nil)
+}
+
+type encrypter struct {
+	aeads []cipher.AEAD
+}
+
+// NewEncrypter returns a Codec which encrypts values with AES-GCM, keys must be 16, 24 or 32 bytes.
+// The first key is used to encrypt, and all keys are used to decrypt, so keys can be rotated smoothly.
+func NewEncrypter(keys ...[]byte) (Codec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("session: encrypter requires at least one key")
+	}
+
+	e := &encrypter{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		e.aeads = append(e.aeads, aead)
+	}
+	return e, nil
+}
+
+func (e *encrypter) Encode(value []byte) (string, error) {
+	aead := e.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, value, nil)), nil
+}
+
+func (e *encrypter) Decode(value string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+
+	for _, aead := range e.aeads {
+		if n := aead.NonceSize(); len(b) > n {
+			if v, err := aead.Open(nil, b[:n], b[n:], nil); err == nil {
+				return v, nil
+			}
+		}
+	}
+	return nil, ErrInvalidCookie
+}
@@ -0,0 +1,34 @@
+package session
+
+import (
+	"github.com/cuigh/auxo/errors"
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/net/web/filter"
+)
+
+const csrfKey = "_csrf"
+
+type csrfStore struct{}
+
+// CSRFStore returns a filter.CSRFStore which keeps tokens in session, it implements the synchronizer token pattern.
+// Session filter must be applied before CSRF filter.
+func CSRFStore() filter.CSRFStore {
+	return csrfStore{}
+}
+
+func (csrfStore) Load(ctx web.Context) string {
+	if s := ctx.Session(); s != nil {
+		token, _ := s.Get(csrfKey).(string)
+		return token
+	}
+	return ""
+}
+
+func (csrfStore) Save(ctx web.Context, token string) error {
+	s := ctx.Session()
+	if s == nil {
+		return errors.New("session: session filter must be applied before CSRF filter")
+	}
+	s.Set(csrfKey, token)
+	return nil
+}
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in memory, sessions are lost when the process restarts.
+type MemoryStore struct {
+	locker sync.RWMutex
+	items  map[string]*memoryItem
+}
+
+type memoryItem struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemoryStore creates a MemoryStore instance.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		items: make(map[string]*memoryItem),
+	}
+	go s.removeExpired()
+	return s
+}
+
+// Load implements Store interface.
+func (s *MemoryStore) Load(id string) ([]byte, error) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+
+	if item := s.items[id]; item != nil && time.Now().Before(item.expires) {
+		return item.data, nil
+	}
+	return nil, nil
+}
+
+// Save implements Store interface.
+func (s *MemoryStore) Save(id string, data []byte, ttl time.Duration) error {
+	s.locker.Lock()
+	s.items[id] = &memoryItem{data: data, expires: time.Now().Add(ttl)}
+	s.locker.Unlock()
+	return nil
+}
+
+// Touch implements Store interface.
+func (s *MemoryStore) Touch(id string, ttl time.Duration) error {
+	s.locker.Lock()
+	if item := s.items[id]; item != nil {
+		item.expires = time.Now().Add(ttl)
+	}
+	s.locker.Unlock()
+	return nil
+}
+
+// Delete implements Store interface.
+func (s *MemoryStore) Delete(id string) error {
+	s.locker.Lock()
+	delete(s.items, id)
+	s.locker.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) removeExpired() {
+	for {
+		time.Sleep(time.Minute)
+
+		now := time.Now()
+		s.locker.Lock()
+		for id, item := range s.items {
+			if now.After(item.expires) {
+				delete(s.items, id)
+			}
+		}
+		s.locker.Unlock()
+	}
+}
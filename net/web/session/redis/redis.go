@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cuigh/auxo/db/redis"
+)
+
+type Options struct {
+	Prefix string
+}
+
+func (o *Options) ensure(opts ...Options) {
+	if len(opts) > 0 {
+		*o = opts[0]
+	}
+
+	if o.Prefix == "" {
+		o.Prefix = "session:"
+	} else if !strings.HasSuffix(o.Prefix, ":") {
+		o.Prefix = o.Prefix + ":"
+	}
+}
+
+// Store keeps sessions in redis, it implements session.Store interface.
+type Store struct {
+	Options
+	redis.Client
+}
+
+// New creates a Store with redis config name db.
+func New(db string, opts ...Options) (*Store, error) {
+	c, err := redis.Open(db)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		Client: c,
+	}
+	s.Options.ensure(opts...)
+	return s, nil
+}
+
+func (s *Store) Load(id string) ([]byte, error) {
+	b, err := s.Client.Get(s.Prefix + id).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (s *Store) Save(id string, data []byte, ttl time.Duration) error {
+	return s.Client.Set(s.Prefix+id, data, ttl).Err()
+}
+
+func (s *Store) Touch(id string, ttl time.Duration) error {
+	return s.Client.Expire(s.Prefix+id, ttl).Err()
+}
+
+func (s *Store) Delete(id string) error {
+	return s.Client.Del(s.Prefix + id).Err()
+}
@@ -0,0 +1,465 @@
+// Package session provides server-side sessions for web applications.
+//
+// Values are encoded with gob, so custom types stored in sessions must be registered with `gob.Register`.
+package session
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cuigh/auxo/log"
+	"github.com/cuigh/auxo/net/web"
+)
+
+const PkgName = "auxo.net.web.session"
+
+const flashPrefix = "_flash."
+
+// Store persists session data.
+type Store interface {
+	// Load returns data of session id, it returns nil if the session doesn't exist or has expired.
+	Load(id string) ([]byte, error)
+	// Save saves data of session id with ttl.
+	Save(id string, data []byte, ttl time.Duration) error
+	// Touch extends ttl of session id.
+	Touch(id string, ttl time.Duration) error
+	// Delete removes session id.
+	Delete(id string) error
+}
+
+// CookieStore keeps whole session data in the cookie instead of server, so it requires a Codec
+// to protect data. Its Store methods do nothing. Cookies are limited to about 4KB, so only small
+// values should be stored, and values changed after response header is sent are lost.
+type CookieStore struct{}
+
+// Load implements Store interface.
+func (CookieStore) Load(string) ([]byte, error) { return nil, nil }
+
+// Save implements Store interface.
+func (CookieStore) Save(string, []byte, time.Duration) error { return nil }
+
+// Touch implements Store interface.
+func (CookieStore) Touch(string, time.Duration) error { return nil }
+
+// Delete implements Store interface.
+func (CookieStore) Delete(string) error { return nil }
+
+// Filter loads session for every request, it can be retrieved by `ctx.Session()`.
+type Filter struct {
+	// Store persists session data.
+	// Required.
+	Store Store
+
+	// Codec encodes session cookies, it is required by CookieStore. With other stores
+	// session IDs are random enough, but a Codec can still be used to sign them.
+	// Optional.
+	Codec Codec
+
+	CookieName   string // default '_s'
+	CookieDomain string
+	CookiePath   string // default '/'
+	CookieSecure bool
+	SameSite     http.SameSite // default Lax
+
+	// IdleTimeout expires sessions which are inactive for a period (sliding expiration).
+	// Optional. Default value 30 minutes.
+	IdleTimeout time.Duration
+
+	// MaxLifetime expires sessions after a period since creation, regardless of activity (absolute expiration).
+	// Optional. Default value 0, which means no limit.
+	MaxLifetime time.Duration
+
+	// Persistent keeps cookie after browser is closed until session is expired.
+	Persistent bool
+
+	once   sync.Once
+	client bool
+	locks  [64]sync.Mutex
+	logger log.Logger
+}
+
+// New creates a session filter with store.
+func New(store Store) *Filter {
+	return &Filter{Store: store}
+}
+
+// Apply implements `web.Filter` interface.
+func (f *Filter) Apply(next web.HandlerFunc) web.HandlerFunc {
+	if f.Store == nil {
+		panic("session filter requires a store")
+	}
+	f.once.Do(f.init)
+
+	return func(ctx web.Context) (err error) {
+		s := f.load(ctx)
+		ctx.SetSession(s)
+		ctx.Response().Before(func() {
+			f.commit(ctx, s, true)
+		})
+
+		err = next(ctx)
+
+		// the header may be committed later by ErrorHandler if handler failed
+		f.commit(ctx, s, err == nil && !ctx.Response().Committed())
+		return
+	}
+}
+
+func (f *Filter) init() {
+	if _, ok := f.Store.(CookieStore); ok {
+		if f.Codec == nil {
+			panic("session: CookieStore requires a codec")
+		}
+		f.client = true
+	}
+	if f.CookieName == "" {
+		f.CookieName = "_s"
+	}
+	if f.CookiePath == "" {
+		f.CookiePath = "/"
+	}
+	if f.SameSite == 0 {
+		f.SameSite = http.SameSiteLaxMode
+	}
+	if f.IdleTimeout <= 0 {
+		f.IdleTimeout = 30 * time.Minute
+	}
+	f.logger = log.Get(PkgName)
+}
+
+// load restores session from cookie, a new session is created if it is absent or expired.
+func (f *Filter) load(ctx web.Context) *session {
+	if cookie, err := ctx.Cookie(f.CookieName); err == nil && cookie.Value != "" {
+		if s, err := f.restore(cookie.Value); err != nil {
+			f.logger.Debugf("session > failed to restore session: %v", err)
+		} else if s != nil {
+			return s
+		}
+	}
+	return newSession(f.client)
+}
+
+func (f *Filter) restore(value string) (*session, error) {
+	var (
+		b   = []byte(value)
+		err error
+	)
+	if f.Codec != nil {
+		if b, err = f.Codec.Decode(value); err != nil {
+			return nil, err
+		}
+	}
+
+	var id string
+	if !f.client {
+		id = string(b)
+		if b, err = f.Store.Load(id); err != nil || b == nil {
+			return nil, err
+		}
+	}
+
+	r, err := decodeRecord(b)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if (f.MaxLifetime > 0 && now.After(r.Created.Add(f.MaxLifetime))) || (f.client && now.After(r.Expires)) {
+		return nil, nil
+	}
+	return &session{id: id, values: r.Values, created: r.Created}, nil
+}
+
+// commit saves modified session, and sets cookie if cookie is true.
+func (f *Filter) commit(ctx web.Context, s *session, cookie bool) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	var err error
+	if f.client {
+		// rewrite cookie of existing session to slide its expiry
+		if cookie && (s.dirty() || !s.isNew) {
+			err = f.setCookie(ctx, s, true)
+		}
+	} else if err = f.save(s); err == nil && cookie && (s.renewed || (f.Persistent && !s.isNew)) {
+		err = f.setCookie(ctx, s, false)
+	}
+	if err != nil {
+		f.logger.Errorf("session > failed to save session: %v", err)
+	}
+}
+
+func (f *Filter) save(s *session) error {
+	if s.oldID != "" {
+		if err := f.Store.Delete(s.oldID); err != nil {
+			return err
+		}
+		s.oldID = ""
+	}
+
+	if !s.dirty() {
+		if !s.isNew && !s.touched {
+			s.touched = true
+			return f.Store.Touch(s.id, f.ttl(s))
+		}
+		return nil
+	}
+
+	lock := f.lock(s.id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	values := s.values
+	if !s.isNew && !s.full {
+		// merge changes into latest values, so concurrent requests don't overwrite each other
+		b, err := f.Store.Load(s.id)
+		if err != nil {
+			return err
+		}
+		if b != nil {
+			if r, err := decodeRecord(b); err == nil {
+				values = s.apply(r.Values)
+			}
+		}
+	}
+
+	b, err := encodeRecord(&record{Values: values, Created: s.created})
+	if err != nil {
+		return err
+	}
+	if err = f.Store.Save(s.id, b, f.ttl(s)); err == nil {
+		if s.isNew {
+			s.isNew, s.renewed = false, true
+		}
+		s.changes, s.full, s.touched = nil, false, true
+	}
+	return err
+}
+
+func (f *Filter) setCookie(ctx web.Context, s *session, client bool) (err error) {
+	c := &http.Cookie{
+		Name:     f.CookieName,
+		Domain:   f.CookieDomain,
+		Path:     f.CookiePath,
+		Secure:   f.CookieSecure,
+		HttpOnly: true,
+		SameSite: f.SameSite,
+	}
+
+	ttl := f.ttl(s)
+	if client && len(s.values) == 0 {
+		c.MaxAge = -1
+	} else {
+		var b []byte
+		if client {
+			b, err = encodeRecord(&record{Values: s.values, Created: s.created, Expires: time.Now().Add(ttl)})
+			if err != nil {
+				return err
+			}
+		} else {
+			b = []byte(s.id)
+		}
+
+		c.Value = string(b)
+		if f.Codec != nil {
+			if c.Value, err = f.Codec.Encode(b); err != nil {
+				return err
+			}
+		}
+		if f.Persistent {
+			c.MaxAge = int(ttl / time.Second)
+		}
+	}
+	ctx.SetCookie(c)
+
+	s.changes, s.full, s.renewed = nil, false, false
+	return nil
+}
+
+// ttl returns the lifetime of session in store, it is limited by MaxLifetime.
+func (f *Filter) ttl(s *session) time.Duration {
+	ttl := f.IdleTimeout
+	if f.MaxLifetime > 0 {
+		if left := time.Until(s.created.Add(f.MaxLifetime)); left < ttl {
+			ttl = left
+		}
+	}
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	return ttl
+}
+
+func (f *Filter) lock(id string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	return &f.locks[h.Sum32()%uint32(len(f.locks))]
+}
+
+type record struct {
+	Values  map[string]interface{}
+	Created time.Time
+	Expires time.Time // only used by CookieStore
+}
+
+func encodeRecord(r *record) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeRecord(b []byte) (*record, error) {
+	r := &record{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(r); err != nil {
+		return nil, err
+	}
+	if r.Values == nil {
+		r.Values = make(map[string]interface{})
+	}
+	return r, nil
+}
+
+type change struct {
+	value   interface{}
+	deleted bool
+}
+
+// session implements web.Session interface.
+type session struct {
+	locker  sync.Mutex
+	id      string
+	oldID   string // ID before regenerating, it should be removed from store
+	values  map[string]interface{}
+	changes map[string]change
+	created time.Time
+	isNew   bool
+	full    bool // values should be saved entirely instead of merging changes
+	renewed bool // ID is changed, cookie should be sent
+	touched bool
+}
+
+func newSession(client bool) *session {
+	s := &session{
+		values:  make(map[string]interface{}),
+		created: time.Now(),
+		isNew:   true,
+	}
+	if !client {
+		s.id = newID()
+	}
+	return s
+}
+
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (s *session) ID() string {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	return s.id
+}
+
+func (s *session) Get(key string) interface{} {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	return s.values[key]
+}
+
+func (s *session) Set(key string, value interface{}) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.set(key, value)
+}
+
+func (s *session) Delete(key string) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.delete(key)
+}
+
+func (s *session) Flash(key string) interface{} {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	key = flashPrefix + key
+	value, ok := s.values[key]
+	if ok {
+		s.delete(key)
+	}
+	return value
+}
+
+func (s *session) SetFlash(key string, value interface{}) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.set(flashPrefix+key, value)
+}
+
+func (s *session) Regenerate() {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	if s.id != "" {
+		if !s.isNew && s.oldID == "" {
+			s.oldID = s.id
+		}
+		s.id = newID()
+	}
+	s.created = time.Now()
+	s.isNew, s.full = true, true
+}
+
+func (s *session) Clear() {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	s.values = make(map[string]interface{})
+	s.changes = nil
+	s.full = true
+}
+
+func (s *session) set(key string, value interface{}) {
+	s.values[key] = value
+	s.track(key, change{value: value})
+}
+
+func (s *session) delete(key string) {
+	delete(s.values, key)
+	s.track(key, change{deleted: true})
+}
+
+func (s *session) track(key string, c change) {
+	if s.changes == nil {
+		s.changes = make(map[string]change)
+	}
+	s.changes[key] = c
+}
+
+func (s *session) dirty() bool {
+	return s.full || len(s.changes) > 0
+}
+
+// apply applies changes to values and returns the result.
+func (s *session) apply(values map[string]interface{}) map[string]interface{} {
+	for k, c := range s.changes {
+		if c.deleted {
+			delete(values, k)
+		} else {
+			values[k] = c.value
+		}
+	}
+	s.values = values
+	return values
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func newServer(f *Filter) *web.Server {
+	s := web.Default()
+	s.Use(f)
+	s.Get("/get", func(ctx web.Context) error {
+		v, _ := ctx.Session().Get(ctx.Q("key")).(string)
+		return ctx.Text(v)
+	})
+	s.Get("/set", func(ctx web.Context) error {
+		ctx.Session().Set(ctx.Q("key"), ctx.Q("value"))
+		return ctx.Text("ok")
+	})
+	s.Get("/flash", func(ctx web.Context) error {
+		if v := ctx.Q("value"); v != "" {
+			ctx.Session().SetFlash("msg", v)
+			return ctx.Text("ok")
+		}
+		v, _ := ctx.Session().Flash("msg").(string)
+		return ctx.Text(v)
+	})
+	s.Get("/login", func(ctx web.Context) error {
+		ctx.Session().Regenerate()
+		return ctx.Text(ctx.Session().ID())
+	})
+	s.Get("/logout", func(ctx web.Context) error {
+		ctx.Session().Clear()
+		return ctx.Text("ok")
+	})
+	return s
+}
+
+type client struct {
+	s      *web.Server
+	cookie *http.Cookie
+}
+
+func (c *client) get(url string) string {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	if c.cookie != nil {
+		req.AddCookie(c.cookie)
+	}
+	rec := httptest.NewRecorder()
+	c.s.ServeHTTP(rec, req)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			c.cookie = nil
+		} else {
+			c.cookie = cookie
+		}
+	}
+	return rec.Body.String()
+}
+
+func TestFilter(t *testing.T) {
+	encrypter, err := NewEncrypter([]byte("0123456789abcdef"))
+	assert.NoError(t, err)
+
+	filters := []*Filter{
+		New(NewMemoryStore()),
+		{Store: NewMemoryStore(), Codec: NewSigner([]byte("secret"))},
+		{Store: CookieStore{}, Codec: encrypter},
+	}
+	for _, f := range filters {
+		c := &client{s: newServer(f)}
+		assert.Equal(t, "", c.get("/get?key=name"))
+		assert.True(t, c.cookie == nil)
+
+		c.get("/set?key=name&value=auxo")
+		assert.NotNil(t, c.cookie)
+		assert.Equal(t, "auxo", c.get("/get?key=name"))
+
+		c.get("/flash?value=hello")
+		assert.Equal(t, "hello", c.get("/flash"))
+		assert.Equal(t, "", c.get("/flash"))
+
+		c.get("/login")
+		assert.Equal(t, "auxo", c.get("/get?key=name"))
+
+		c.get("/logout")
+		assert.Equal(t, "", c.get("/get?key=name"))
+	}
+}
+
+func TestFilter_Regenerate(t *testing.T) {
+	store := NewMemoryStore()
+	c := &client{s: newServer(New(store))}
+	c.get("/set?key=name&value=auxo")
+	old := c.cookie.Value
+
+	id := c.get("/login")
+	assert.Equal(t, id, c.cookie.Value)
+	assert.True(t, id != old)
+	b, _ := store.Load(old)
+	assert.True(t, b == nil)
+
+	// old session can't be used any more
+	c2 := &client{s: c.s, cookie: &http.Cookie{Name: "_s", Value: old}}
+	assert.Equal(t, "", c2.get("/get?key=name"))
+}
+
+func TestFilter_Merge(t *testing.T) {
+	loaded, proceed := make(chan struct{}), make(chan struct{})
+	s := newServer(New(NewMemoryStore()))
+	s.Get("/slow", func(ctx web.Context) error {
+		close(loaded)
+		<-proceed
+		ctx.Session().Set("b", "2")
+		return ctx.Text("ok")
+	})
+
+	c := &client{s: s}
+	c.get("/set?key=a&value=1")
+
+	// the slow request loads session before the other request changes it
+	done := make(chan struct{})
+	go func() {
+		(&client{s: s, cookie: c.cookie}).get("/slow")
+		close(done)
+	}()
+	<-loaded
+	(&client{s: s, cookie: c.cookie}).get("/set?key=c&value=3")
+	close(proceed)
+	<-done
+
+	assert.Equal(t, "1", c.get("/get?key=a"))
+	assert.Equal(t, "2", c.get("/get?key=b"))
+	assert.Equal(t, "3", c.get("/get?key=c"))
+}
+
+func TestFilter_MaxLifetime(t *testing.T) {
+	c := &client{s: newServer(&Filter{Store: NewMemoryStore(), MaxLifetime: time.Second})}
+	c.get("/set?key=name&value=auxo")
+	assert.Equal(t, "auxo", c.get("/get?key=name"))
+
+	time.Sleep(time.Second)
+	assert.Equal(t, "", c.get("/get?key=name"))
+}
+
+func TestCodec(t *testing.T) {
+	old := NewSigner([]byte("old"))
+	signer := NewSigner([]byte("new"), []byte("old"))
+
+	s, err := old.Encode([]byte("auxo"))
+	assert.NoError(t, err)
+	b, err := signer.Decode(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "auxo", string(b))
+
+	_, err = signer.Decode(s[:len(s)-1])
+	assert.Error(t, err)
+
+	encrypter, err := NewEncrypter([]byte("0123456789abcdef"))
+	assert.NoError(t, err)
+	s, err = encrypter.Encode([]byte("auxo"))
+	assert.NoError(t, err)
+	b, err = encrypter.Decode(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "auxo", string(b))
+}
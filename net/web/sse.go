@@ -0,0 +1,175 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuigh/auxo/errors"
+)
+
+// ErrStreamClosed is returned when writing to a closed event stream.
+var ErrStreamClosed = errors.New("event stream closed")
+
+// EventStream is a writer of Server-Sent Events.
+type EventStream struct {
+	w        *responseWriter
+	r        *http.Request
+	timeout  time.Duration
+	locker   sync.Mutex
+	closed   chan struct{}
+	isClosed bool
+}
+
+func newEventStream(c *context, heartbeat time.Duration) *EventStream {
+	s := &EventStream{
+		w:       c.response,
+		r:       c.request,
+		timeout: c.server.cfg.WriteTimeout,
+		closed:  make(chan struct{}),
+	}
+
+	h := s.w.Header()
+	h.Set(HeaderContentType, MIMETextEventStream)
+	h.Set(HeaderCacheControl, "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	s.extend()
+	s.w.CommitHeader()
+	s.w.Flush()
+
+	go s.watch(heartbeat)
+	return s
+}
+
+// LastEventID returns the `Last-Event-ID` header sent by a reconnecting client.
+func (s *EventStream) LastEventID() string {
+	return s.r.Header.Get(HeaderLastEventID)
+}
+
+// Done returns a channel that's closed when the client disconnects or the stream is closed.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.closed
+}
+
+// Send sends an event to client, event and id are optional.
+// data is sent as is if it is a string or []byte, otherwise it is encoded as JSON.
+func (s *EventStream) Send(event, id string, data interface{}) error {
+	var text string
+	switch v := data.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		text = string(b)
+	}
+
+	var sb strings.Builder
+	if id != "" {
+		sb.WriteString("id: " + sanitize(id) + "\n")
+	}
+	if event != "" {
+		sb.WriteString("event: " + sanitize(event) + "\n")
+	}
+	// clients treat CRLF, CR and LF as line endings, so all of them must be split to avoid injecting fields
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(text, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteByte('\n')
+	return s.write(sb.String())
+}
+
+// Retry tells client how long to wait before reconnecting.
+func (s *EventStream) Retry(d time.Duration) error {
+	return s.write("retry: " + strconv.FormatInt(d.Milliseconds(), 10) + "\n\n")
+}
+
+// Comment sends a comment line which is ignored by client.
+func (s *EventStream) Comment(text string) error {
+	return s.write(": " + sanitize(text) + "\n\n")
+}
+
+// Close stops the stream, it is called automatically when handler returns.
+func (s *EventStream) Close() {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	if !s.isClosed {
+		s.isClosed = true
+		close(s.closed)
+	}
+}
+
+func (s *EventStream) write(text string) (err error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	if s.isClosed {
+		return ErrStreamClosed
+	}
+
+	s.extend()
+	if _, err = s.w.WriteString(text); err == nil {
+		s.w.Flush()
+	}
+	return
+}
+
+// extend extends write deadline of the connection for next event.
+func (s *EventStream) extend() {
+	if s.timeout > 0 {
+		_ = setWriteDeadline(s.w, time.Now().Add(s.timeout))
+	}
+}
+
+// watch sends heartbeat comments periodically and closes the stream when client disconnects.
+func (s *EventStream) watch(heartbeat time.Duration) {
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	done := s.r.Context().Done()
+	for {
+		select {
+		case <-tick:
+			if err := s.write(": ping\n\n"); err != nil {
+				s.Close()
+				return
+			}
+		case <-done:
+			s.Close()
+			return
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func sanitize(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// setWriteDeadline sets write deadline of the underlying connection, it works like http.ResponseController.
+func setWriteDeadline(w http.ResponseWriter, t time.Time) error {
+	for {
+		switch v := w.(type) {
+		case interface{ SetWriteDeadline(time.Time) error }:
+			return v.SetWriteDeadline(t)
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return http.ErrNotSupported
+		}
+	}
+}
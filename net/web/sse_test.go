@@ -0,0 +1,57 @@
+package web
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestContext_SSE(t *testing.T) {
+	s := Default()
+	s.Get("/events", func(ctx Context) error {
+		stream := ctx.SSE(10 * time.Millisecond)
+		if err := stream.Retry(time.Second); err != nil {
+			return err
+		}
+		if err := stream.Send("greeting", stream.LastEventID()+"1", "hello\nworld\revent: admin\r\nend"); err != nil {
+			return err
+		}
+		time.Sleep(15 * time.Millisecond)
+		return stream.Send("", "", map[string]int{"n": 1})
+	})
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/events", nil)
+	req.Header.Set(HeaderLastEventID, "0")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, MIMETextEventStream, resp.Header.Get(HeaderContentType))
+
+	var (
+		lines []string
+		pings int
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); line == ": ping" {
+			pings++
+			scanner.Scan()
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	expected := []string{
+		"retry: 1000", "",
+		"id: 01", "event: greeting", "data: hello", "data: world", "data: event: admin", "data: end", "",
+		`data: {"n":1}`, "",
+	}
+	assert.Equal(t, expected, lines)
+	assert.True(t, pings > 0)
+}
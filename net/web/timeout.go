@@ -0,0 +1,198 @@
+package web
+
+import (
+	"bufio"
+	scontext "context"
+	serrors "errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cuigh/auxo/data"
+	"github.com/cuigh/auxo/errors"
+)
+
+// TimeoutHandler returns a HandlerFunc which runs next with a deadline attached to `Request().Context()`.
+// If next doesn't finish in time, ErrTimeout is returned to ErrorHandler unless the response is already committed.
+// next runs in a separate goroutine with a copy of context, writes it makes after timeout are discarded
+// and fail with http.ErrHandlerTimeout, so handlers should stop as soon as the request context is done.
+func TimeoutHandler(next HandlerFunc, d time.Duration) HandlerFunc {
+	return func(ctx Context) error {
+		c, ok := ctx.(*context)
+		if !ok || d <= 0 {
+			return next(ctx)
+		}
+
+		tc, cancel := scontext.WithTimeout(c.request.Context(), d)
+		defer cancel()
+
+		tw := newTimeoutWriter(c.response)
+		nc := c.clone(tw, c.request.WithContext(tc))
+		done := make(chan timeoutResult, 1)
+		go func() {
+			var r timeoutResult
+			defer func() {
+				if r.panic = recover(); r.panic != nil {
+					r.err = errors.Convert(r.panic)
+				}
+				if tw.finish() {
+					// the request is abandoned, so context is released here
+					if r.err != nil {
+						nc.Logger().Errorf("web > Handler failed after timeout: %v", r.err)
+					}
+					c.server.release(nc)
+				} else {
+					done <- r
+				}
+			}()
+			r.err = next(nc)
+		}()
+
+		var r timeoutResult
+		select {
+		case r = <-done:
+		case <-tc.Done():
+			if abandoned, committed := tw.timeout(); abandoned {
+				if committed {
+					c.Logger().Warnf("web > Handler timed out after response was committed: %s", c.request.URL.Path)
+					return nil
+				}
+				return ErrTimeout
+			}
+			// handler finished just before timeout
+			r = <-done
+		}
+
+		c.server.release(nc)
+		if r.panic != nil {
+			panic(r.panic)
+		}
+		if r.err != nil && tc.Err() != nil && serrors.Is(r.err, scontext.DeadlineExceeded) && !c.response.Committed() {
+			return ErrTimeout
+		}
+		return r.err
+	}
+}
+
+type timeoutResult struct {
+	err   error
+	panic interface{}
+}
+
+// clone creates a context for the same request which writes to w.
+func (c *context) clone(w http.ResponseWriter, r *http.Request) *context {
+	nc := c.server.ctxPool.Get(w, r)
+	nc.route = c.route
+	nc.pathNames = c.pathNames
+	copy(nc.pathValues, c.pathValues)
+	nc.handler = c.handler
+	nc.user = c.user
+	nc.session = c.session
+	if c.data != nil {
+		nc.data = make(data.Map, len(c.data))
+		for k, v := range c.data {
+			nc.data[k] = v
+		}
+	}
+	return nc
+}
+
+// timeoutWriter guards the response of a handler, it rejects all writes after timeout.
+// Headers are buffered until commit, so ErrorHandler can write response safely after timeout.
+type timeoutWriter struct {
+	w         *responseWriter
+	h         http.Header
+	mu        sync.Mutex
+	timedOut  bool
+	finished  bool
+	committed bool
+}
+
+func newTimeoutWriter(w *responseWriter) *timeoutWriter {
+	return &timeoutWriter{w: w, h: w.Header().Clone()}
+}
+
+// timeout marks writer as timed out, abandoned is false if handler has already finished.
+func (tw *timeoutWriter) timeout() (abandoned, committed bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.finished {
+		return false, tw.committed
+	}
+	tw.timedOut = true
+	return true, tw.committed
+}
+
+// finish marks handler as finished and reports whether the request was abandoned because of timeout.
+func (tw *timeoutWriter) finish() (abandoned bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.finished = true
+	return tw.timedOut
+}
+
+// commit copies buffered headers to the underlying writer, it must be called with lock held.
+func (tw *timeoutWriter) commit() {
+	if !tw.committed {
+		dst := tw.w.Header()
+		for k := range dst {
+			delete(dst, k)
+		}
+		for k, v := range tw.h {
+			dst[k] = v
+		}
+		tw.committed = true
+	}
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.timedOut {
+		tw.commit()
+		tw.w.WriteHeader(code)
+	}
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.commit()
+	return tw.w.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.timedOut {
+		tw.commit()
+		tw.w.Flush()
+	}
+}
+
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	tw.commit()
+	return tw.w.Hijack()
+}
+
+func (tw *timeoutWriter) Push(target string, opts *http.PushOptions) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	return tw.w.Push(target, opts)
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestTimeoutHandler(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	s := Default()
+	s.Get("/fast", func(ctx Context) error {
+		ctx.SetHeader("X-Fast", "1")
+		return ctx.Text("ok")
+	}, WithTimeout(time.Second))
+	s.Get("/cancel", func(ctx Context) error {
+		<-ctx.Request().Context().Done()
+		return ctx.Request().Context().Err()
+	}, WithTimeout(10*time.Millisecond))
+	s.Get("/ignore", func(ctx Context) error {
+		// keep writing after timeout
+		<-release
+		ctx.SetHeader("X-Late", "1")
+		return ctx.Text("late")
+	}, WithTimeout(10*time.Millisecond))
+	s.Get("/committed", func(ctx Context) error {
+		_ = ctx.Text("partial")
+		<-release
+		return nil
+	}, WithTimeout(10*time.Millisecond))
+
+	cases := []struct {
+		Path   string
+		Status int
+		Body   string
+	}{
+		{"/fast", http.StatusOK, "ok"},
+		{"/cancel", http.StatusServiceUnavailable, ""},
+		{"/ignore", http.StatusServiceUnavailable, ""},
+		{"/committed", http.StatusOK, "partial"},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.Path, nil))
+		assert.Equal(t, c.Status, rec.Code, c.Path)
+		if c.Body != "" {
+			assert.Equal(t, c.Body, rec.Body.String(), c.Path)
+		}
+		assert.Equal(t, "", rec.Header().Get("X-Late"))
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, "1", rec.Header().Get("X-Fast"))
+}
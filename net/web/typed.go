@@ -0,0 +1,105 @@
+package web
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// Typed creates a HandlerFunc from a typed function, it removes the boilerplate of binding and rendering.
+//
+//	s.Post("/users", web.Typed(func(ctx web.Context, req *CreateUserRequest) (*User, error) {
+//		return biz.CreateUser(req)
+//	}))
+//
+// Req is bound by Binder and validated by Validator if it is set, binding is skipped if Req is an empty struct.
+// Resp is encoded by content negotiation with the status set by handler (200 by default), if Resp is an
+// empty struct, 204 is written. Errors are handled by ErrorHandler. Use HandleTyped to record both types to
+// route info for API documents.
+func Typed[Req, Resp any](fn func(ctx Context, req Req) (Resp, error)) HandlerFunc {
+	return typed(fn, 0)
+}
+
+// HandleTyped registers a handler created by Typed on router, request and response types are recorded as if
+// they were declared with WithRequest and WithResponse, so they can be used to generate API documents.
+//
+//	web.HandleTyped(s, http.MethodPost, "/users", createUser, http.StatusCreated, web.WithName("user.create"))
+//
+// status is the code of successful responses, it is used unless fn sets another one. 0 means the default of
+// Typed: 200, or 204 if Resp is an empty struct.
+func HandleTyped[Req, Resp any](r Router, method, path string, fn func(ctx Context, req Req) (Resp, error),
+	status int, opts ...HandlerOption) {
+	opts = append([]HandlerOption{withTyped(fn, status)}, opts...)
+	r.Match([]string{method}, path, typed(fn, status), opts...)
+}
+
+func typed[Req, Resp any](fn func(ctx Context, req Req) (Resp, error), status int) HandlerFunc {
+	reqType, respType := reflect.TypeOf((*Req)(nil)).Elem(), reflect.TypeOf((*Resp)(nil)).Elem()
+	bind, noContent := !isEmptyStruct(reqType), isEmptyStruct(respType)
+
+	return func(ctx Context) error {
+		var req Req
+		if bind {
+			if reqType.Kind() == reflect.Ptr {
+				req = reflect.New(reqType.Elem()).Interface().(Req)
+				if err := ctx.Bind(req, ctx.Server().Validator != nil); err != nil {
+					return err
+				}
+			} else if err := ctx.Bind(&req, ctx.Server().Validator != nil); err != nil {
+				return err
+			}
+		}
+
+		resp, err := fn(ctx, req)
+		if err != nil || ctx.Response().Committed() {
+			return err
+		}
+		if noContent {
+			code := http.StatusNoContent
+			if status != 0 {
+				code = status
+			}
+			ctx.Response().WriteHeader(code)
+			return nil
+		}
+		code := ctx.Response().Status()
+		if status != 0 && code == http.StatusOK {
+			code = status
+		}
+		return ctx.Negotiate(code, resp)
+	}
+}
+
+// withTyped declares request and response types of fn, see HandleTyped.
+func withTyped[Req, Resp any](_ func(ctx Context, req Req) (Resp, error), status int) HandlerOption {
+	reqType, respType := reflect.TypeOf((*Req)(nil)).Elem(), reflect.TypeOf((*Resp)(nil)).Elem()
+	var req, resp interface{}
+	if !isEmptyStruct(reqType) {
+		req = zero(reqType)
+	}
+	if isEmptyStruct(respType) {
+		if status == 0 {
+			status = http.StatusNoContent
+		}
+	} else {
+		resp = zero(respType)
+		if status == 0 {
+			status = http.StatusOK
+		}
+	}
+	return func(info *handlerInfo) {
+		WithRequest(req)(info)
+		WithResponse(status, resp)(info)
+	}
+}
+
+func isEmptyStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.NumField() == 0
+}
+
+// zero returns a typed zero value for documents, nil is returned for interface types.
+func zero(t reflect.Type) interface{} {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	return reflect.Zero(t).Interface()
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cuigh/auxo/data/valid"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+func TestTyped(t *testing.T) {
+	type request struct {
+		ID   int    `json:"id"`
+		Name string `json:"name" valid:"required"`
+	}
+	type response struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	update := func(ctx Context, req *request) (*response, error) {
+		return &response{ID: req.ID, Name: req.Name}, nil
+	}
+	remove := func(ctx Context, req struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}
+	// status is declared by HandleTyped
+	create := func(ctx Context, req request) (response, error) {
+		return response{Name: req.Name}, nil
+	}
+
+	s := Default()
+	s.Validator = &valid.Validator{}
+	HandleTyped(s, http.MethodPut, "/users/:id", update, 0, WithName("update"))
+	HandleTyped(s, http.MethodDelete, "/users/:id", remove, 0, WithName("delete"))
+	HandleTyped(s.Group("/v2"), http.MethodPost, "/users", create, http.StatusCreated, WithName("create"),
+		WithResponse(http.StatusConflict, nil))
+	s.Get("/users/:id", Typed(update), WithName("plain"))
+
+	cases := []struct {
+		Method string
+		Path   string
+		Body   string
+		Accept string
+		Status int
+		Result string
+	}{
+		{http.MethodPut, "/users/1", `{"id":1,"name":"a"}`, "", http.StatusOK, `{"id":1,"name":"a"}`},
+		{http.MethodPut, "/users/1", `{"name":"b"}`, MIMEApplicationXML, http.StatusOK, "<response>"},
+		{http.MethodPut, "/users/1", `{}`, "", http.StatusBadRequest, ""},
+		{http.MethodPost, "/v2/users", `{"name":"c"}`, "", http.StatusCreated, `"name":"c"`},
+		{http.MethodDelete, "/users/1", "", "", http.StatusNoContent, ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.Method, c.Path, strings.NewReader(c.Body))
+		req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+		if c.Accept != "" {
+			req.Header.Set(HeaderAccept, c.Accept)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, c.Status, rec.Code, c.Method+" "+c.Path)
+		assert.Contains(t, rec.Body.String(), c.Result)
+	}
+
+	h := s.routes["update"].Handler().(HandlerInfo)
+	assert.Equal(t, (*request)(nil), h.Request())
+	assert.Equal(t, (*response)(nil), h.Responses()[http.StatusOK])
+	h = s.routes["delete"].Handler().(HandlerInfo)
+	assert.Nil(t, h.Request())
+	assert.Equal(t, 1, len(h.Responses()))
+	assert.Nil(t, h.Responses()[http.StatusNoContent])
+	h = s.routes["create"].Handler().(HandlerInfo)
+	assert.Equal(t, request{}, h.Request())
+	assert.Equal(t, 2, len(h.Responses()))
+	assert.Equal(t, response{}, h.Responses()[http.StatusCreated])
+	h = s.routes["plain"].Handler().(HandlerInfo)
+	assert.Nil(t, h.Request())
+	assert.Nil(t, h.Responses())
+}
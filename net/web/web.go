@@ -23,16 +23,25 @@ const (
 	MIMEApplicationXML                   = "application/xml"
 	MIMEApplicationXMLCharsetUTF8        = MIMEApplicationXML + "; " + charsetUTF8
 	MIMEApplicationForm                  = "application/x-www-form-urlencoded"
+	MIMEApplicationProblemJSON           = "application/problem+json"
+	MIMEApplicationYAML                  = "application/yaml"
+	MIMEApplicationYAMLCharsetUTF8       = MIMEApplicationYAML + "; " + charsetUTF8
+	MIMEApplicationMsgpack               = "application/msgpack"
+	MIMEApplicationProtobuf              = "application/protobuf"
+	MIMETextXML                          = "text/xml"
+	MIMETextXMLCharsetUTF8               = MIMETextXML + "; " + charsetUTF8
 	MIMETextHTML                         = "text/html"
 	MIMETextHTMLCharsetUTF8              = MIMETextHTML + "; " + charsetUTF8
 	MIMETextPlain                        = "text/plain"
 	MIMETextPlainCharsetUTF8             = MIMETextPlain + "; " + charsetUTF8
 	MIMEMultipartForm                    = "multipart/form-data"
 	MIMEOctetStream                      = "application/octet-stream"
+	MIMETextEventStream                  = "text/event-stream"
 )
 
 // Headers
 const (
+	HeaderAccept              = "Accept"
 	HeaderAcceptEncoding      = "Accept-Encoding"
 	HeaderAcceptLanguage      = "Accept-Language"
 	HeaderAllow               = "Allow"
@@ -45,13 +54,16 @@ const (
 	HeaderSetCookie           = "Set-Cookie"
 	HeaderIfModifiedSince     = "If-Modified-Since"
 	HeaderLastModified        = "Last-Modified"
+	HeaderLastEventID         = "Last-Event-ID"
 	HeaderLocation            = "Location"
 	HeaderUpgrade             = "Upgrade"
 	HeaderVary                = "Vary"
 	HeaderWWWAuthenticate     = "WWW-Authenticate"
 	HeaderXForwardedProto     = "X-Forwarded-Proto"
+	HeaderXForwardedHost      = "X-Forwarded-Host"
 	HeaderXHTTPMethodOverride = "X-HTTP-Method-Override"
 	HeaderXForwardedFor       = "X-Forwarded-For"
+	HeaderForwarded           = "Forwarded"
 	HeaderXRealIP             = "X-Real-IP"
 	HeaderXRequestedWith      = "X-Requested-With"
 	HeaderServer              = "Server"
@@ -102,6 +114,26 @@ type ContentDisposition struct {
 
 type User = security.User
 
+// Session is the interface of server-side session, it is available after a session filter is applied.
+type Session interface {
+	// ID returns the session ID.
+	ID() string
+	// Get returns the value of key.
+	Get(key string) interface{}
+	// Set sets the value of key.
+	Set(key string, value interface{})
+	// Delete removes the value of key.
+	Delete(key string)
+	// Flash returns and removes a flash value which is set by previous requests.
+	Flash(key string) interface{}
+	// SetFlash sets a flash value which is available until it is read.
+	SetFlash(key string, value interface{})
+	// Regenerate changes the session ID to prevent session fixation, it should be called after login.
+	Regenerate()
+	// Clear removes all values, it is usually called on logout.
+	Clear()
+}
+
 // Binder is the interface that can unmarshal request data to struct.
 type Binder interface {
 	// Bind takes data out of the request and decodes into a struct according
@@ -135,6 +167,7 @@ type Router interface {
 	Any(path string, handler HandlerFunc, opts ...HandlerOption)
 	Match(methods []string, path string, h HandlerFunc, opts ...HandlerOption)
 	Handle(path string, controller interface{}, filters ...Filter)
+	WebSocket(path string, h WebSocketHandler, opts ...HandlerOption)
 
 	Static(prefix string, fs http.FileSystem, fallback string, filters ...Filter)
 	File(path string, fs http.FileSystem, name string, filters ...Filter)
@@ -0,0 +1,247 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuigh/auxo/byte/size"
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket message types
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+// WebSocket close codes
+const (
+	CloseNormalClosure     = websocket.CloseNormalClosure
+	CloseGoingAway         = websocket.CloseGoingAway
+	CloseInternalServerErr = websocket.CloseInternalServerErr
+)
+
+// WebSocketOptions represents the options of WebSocket handlers.
+type WebSocketOptions struct {
+	// Origins is a list of origins allowed to connect, "*" allows all origins.
+	// Optional. Default only allows requests from the same host.
+	Origins []string
+	// Subprotocols is the server supported protocols in order of preference.
+	Subprotocols []string
+	// ReadLimit is the maximum size of a message read from peer. Default is 1MB.
+	ReadLimit size.Size
+	// PingInterval is the interval of sending ping messages, a negative value disables keepalive. Default is 30s.
+	PingInterval time.Duration
+	// PongTimeout is the time allowed to read the next pong message. Default is PingInterval * 2.
+	PongTimeout      time.Duration
+	HandshakeTimeout time.Duration
+	ReadBufferSize   int
+	WriteBufferSize  int
+	Compression      bool
+}
+
+func (opts *WebSocketOptions) ensure() {
+	if opts.ReadLimit <= 0 {
+		opts.ReadLimit = size.MB
+	}
+	if opts.PingInterval == 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	if opts.PongTimeout <= 0 {
+		opts.PongTimeout = opts.PingInterval * 2
+	}
+}
+
+// WebSocketHandler defines a function to server WebSocket connections.
+type WebSocketHandler func(ctx Context, conn *Conn) error
+
+// Conn represents a WebSocket connection.
+type Conn struct {
+	*websocket.Conn
+	locker sync.Mutex
+	closed bool
+}
+
+// WriteJSON writes the JSON encoding of i as a text message, it is safe for concurrent use.
+func (c *Conn) WriteJSON(i interface{}) error {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.Conn.WriteJSON(i)
+}
+
+// WriteMessage writes a message with the given type and payload, it is safe for concurrent use.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.Conn.WriteMessage(messageType, data)
+}
+
+// Close sends a normal close message to peer and closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.CloseWith(CloseNormalClosure, "")
+}
+
+// CloseWith sends a close message with code and reason to peer and closes the underlying connection.
+func (c *Conn) CloseWith(code int, reason string) error {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = c.Conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	return c.Conn.Close()
+}
+
+func (c *Conn) keepalive(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(interval)); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// IsCloseError returns true if err is a close error with any of the specified codes.
+func IsCloseError(err error, codes ...int) bool {
+	return websocket.IsCloseError(err, codes...)
+}
+
+// WrapWebSocket wraps `WebSocketHandler` into `web.HandlerFunc`.
+// Filters run before the connection is upgraded, so authentication and logging work as usual.
+func WrapWebSocket(h WebSocketHandler, opts WebSocketOptions) HandlerFunc {
+	opts.ensure()
+	upgrader := &websocket.Upgrader{
+		HandshakeTimeout:  opts.HandshakeTimeout,
+		ReadBufferSize:    opts.ReadBufferSize,
+		WriteBufferSize:   opts.WriteBufferSize,
+		Subprotocols:      opts.Subprotocols,
+		EnableCompression: opts.Compression,
+		Error: func(http.ResponseWriter, *http.Request, int, error) {
+			// leave error response to ErrorHandler
+		},
+	}
+	upgrader.CheckOrigin = sameOrigin
+	if len(opts.Origins) > 0 {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get(HeaderOrigin)
+			for _, o := range opts.Origins {
+				if o == "*" || strings.EqualFold(o, origin) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	return func(ctx Context) error {
+		r := ctx.Request()
+		if !websocket.IsWebSocketUpgrade(r) {
+			return NewError(http.StatusBadRequest, "not a websocket handshake")
+		}
+		if !upgrader.CheckOrigin(r) {
+			return NewError(http.StatusForbidden, "origin not allowed")
+		}
+
+		wc, err := upgrader.Upgrade(ctx.Response(), r, nil)
+		if err != nil {
+			return NewError(http.StatusBadRequest, err.Error())
+		}
+		ctx.Status(http.StatusSwitchingProtocols)
+
+		conn := &Conn{Conn: wc}
+		s := ctx.Server()
+		if !s.trackConn(conn, true) {
+			// server is shutting down, connection is upgraded during draining
+			_ = conn.CloseWith(CloseGoingAway, "server shutdown")
+			return nil
+		}
+
+		conn.SetReadLimit(int64(opts.ReadLimit))
+		done := make(chan struct{})
+		if opts.PingInterval > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(opts.PongTimeout))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(opts.PongTimeout))
+			})
+			go conn.keepalive(opts.PingInterval, done)
+		}
+
+		defer func() {
+			close(done)
+			s.trackConn(conn, false)
+		}()
+
+		if err = h(ctx, conn); err != nil {
+			if !IsCloseError(err, CloseNormalClosure, CloseGoingAway) {
+				ctx.Logger().Warnf("web > WebSocket handler [%s] failed: %s", ctx.Route(), err)
+			}
+			_ = conn.CloseWith(CloseInternalServerErr, "")
+		} else {
+			_ = conn.Close()
+		}
+		return nil
+	}
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get(HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	if i := strings.Index(origin, "://"); i >= 0 {
+		origin = origin[i+3:]
+	}
+	return strings.EqualFold(origin, r.Host)
+}
+
+// WebSocket registers a WebSocket route with server's WebSocket options.
+func (s *Server) WebSocket(path string, h WebSocketHandler, opts ...HandlerOption) {
+	s.register(http.MethodGet, path, WrapWebSocket(h, s.cfg.WebSocket), opts...)
+}
+
+// trackConn adds or removes an active connection, it returns false if the connection is added after closeConns.
+func (s *Server) trackConn(c *Conn, add bool) bool {
+	s.connLocker.Lock()
+	defer s.connLocker.Unlock()
+
+	if !add {
+		delete(s.conns, c)
+	} else if s.connClosed {
+		return false
+	} else {
+		if s.conns == nil {
+			s.conns = make(map[*Conn]struct{})
+		}
+		s.conns[c] = struct{}{}
+	}
+	return true
+}
+
+// closeConns sends going away message to all active WebSocket connections and closes them,
+// connections upgraded after it are closed at once.
+func (s *Server) closeConns() {
+	s.connLocker.Lock()
+	s.connClosed = true
+	conns := make([]*Conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.connLocker.Unlock()
+
+	for _, c := range conns {
+		_ = c.CloseWith(CloseGoingAway, "server shutdown")
+	}
+}
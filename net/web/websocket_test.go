@@ -0,0 +1,83 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cuigh/auxo/data"
+	"github.com/cuigh/auxo/test/assert"
+	"github.com/gorilla/websocket"
+)
+
+func TestServer_WebSocket(t *testing.T) {
+	s := Default()
+	s.WebSocket("/ws", func(ctx Context, conn *Conn) error {
+		m := data.Map{}
+		if err := conn.ReadJSON(&m); err != nil {
+			return err
+		}
+		m["user"] = ctx.Get("user")
+		return conn.WriteJSON(m)
+	})
+	s.UseFunc(func(next HandlerFunc) HandlerFunc {
+		return func(ctx Context) error {
+			ctx.Set("user", "auxo")
+			return next(ctx)
+		}
+	})
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	err = conn.WriteJSON(data.Map{"name": "test"})
+	assert.NoError(t, err)
+
+	m := data.Map{}
+	err = conn.ReadJSON(&m)
+	assert.NoError(t, err)
+	assert.Equal(t, "test", m["name"])
+	assert.Equal(t, "auxo", m["user"])
+
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+
+	// origin check
+	header := http.Header{HeaderOrigin: []string{"http://evil.com"}}
+	_, resp, err := websocket.DefaultDialer.Dial(url, header)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestServer_WebSocketClose(t *testing.T) {
+	s := Default()
+	s.WebSocket("/ws", func(ctx Context, conn *Conn) error {
+		_, _, err := conn.ReadMessage()
+		return err
+	})
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	s.closeConns()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+
+	// connections upgraded while draining are closed too
+	conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+}
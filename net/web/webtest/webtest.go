@@ -0,0 +1,336 @@
+// Package webtest drives a web server in memory through `ServeHTTP`, without opening sockets.
+//
+//	c := webtest.New(t, server)
+//	c.Post("/login").Form(url.Values{"name": {"admin"}, "password": {"123"}}).Do().Status(http.StatusFound)
+//	c.Get("/users/1").Header("Accept", "application/json").Do().Status(http.StatusOK).JSON("name", "admin")
+//	c.As(security.NewUser("1", "admin")).Delete("/users/2").Do().Status(http.StatusNoContent)
+//
+// Cookies set by responses are kept by client and sent with subsequent requests.
+package webtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+// Client sends requests to server and keeps cookies between calls.
+type Client struct {
+	tb      testing.TB
+	server  *web.Server
+	base    *url.URL
+	jar     http.CookieJar
+	user    web.User
+	headers http.Header
+}
+
+// servers records servers which the user filter is installed on.
+var servers sync.Map // *web.Server -> struct{}
+
+type userKey struct{}
+
+// New creates a client for the server, requests are sent to host `example.com` by default.
+// A filter is installed on the server at the first call, it sets user of requests made by As.
+func New(tb testing.TB, s *web.Server) *Client {
+	if _, loaded := servers.LoadOrStore(s, struct{}{}); !loaded {
+		s.UseFirst(web.FilterFunc(setUser))
+	}
+
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		tb:      tb,
+		server:  s,
+		base:    &url.URL{Scheme: "http", Host: "example.com"},
+		jar:     jar,
+		headers: make(http.Header),
+	}
+}
+
+// Host returns a copy of client which sends requests to the host, cookies are shared.
+func (c *Client) Host(host string) *Client {
+	n := c.clone()
+	n.base = &url.URL{Scheme: c.base.Scheme, Host: host}
+	return n
+}
+
+// As returns a copy of client whose requests are made by the user, authentication filters are bypassed.
+func (c *Client) As(user web.User) *Client {
+	n := c.clone()
+	n.user = user
+	return n
+}
+
+// SetHeader sets a default header which is sent with every request.
+func (c *Client) SetHeader(key, value string) *Client {
+	c.headers.Set(key, value)
+	return c
+}
+
+// Cookie returns the cookie kept by client, it returns nil if not found.
+func (c *Client) Cookie(name string) *http.Cookie {
+	for _, cookie := range c.jar.Cookies(c.base) {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func (c *Client) clone() *Client {
+	n := *c
+	n.headers = c.headers.Clone()
+	return &n
+}
+
+// Get creates a GET request.
+func (c *Client) Get(path string) *Request {
+	return c.Request(http.MethodGet, path)
+}
+
+// Head creates a HEAD request.
+func (c *Client) Head(path string) *Request {
+	return c.Request(http.MethodHead, path)
+}
+
+// Post creates a POST request.
+func (c *Client) Post(path string) *Request {
+	return c.Request(http.MethodPost, path)
+}
+
+// Put creates a PUT request.
+func (c *Client) Put(path string) *Request {
+	return c.Request(http.MethodPut, path)
+}
+
+// Patch creates a PATCH request.
+func (c *Client) Patch(path string) *Request {
+	return c.Request(http.MethodPatch, path)
+}
+
+// Delete creates a DELETE request.
+func (c *Client) Delete(path string) *Request {
+	return c.Request(http.MethodDelete, path)
+}
+
+// Options creates an OPTIONS request.
+func (c *Client) Options(path string) *Request {
+	return c.Request(http.MethodOptions, path)
+}
+
+// Request creates a request with method and path, path can contain query string.
+func (c *Client) Request(method, path string) *Request {
+	return &Request{
+		c:       c,
+		method:  method,
+		path:    path,
+		headers: c.headers.Clone(),
+		query:   make(url.Values),
+	}
+}
+
+// Request is a builder of HTTP request.
+type Request struct {
+	c       *Client
+	method  string
+	path    string
+	headers http.Header
+	query   url.Values
+	cookies []*http.Cookie
+	body    []byte
+}
+
+// Header sets a request header.
+func (r *Request) Header(key, value string) *Request {
+	r.headers.Set(key, value)
+	return r
+}
+
+// Query adds a query parameter.
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Cookie adds a cookie to request, it is not kept by client.
+func (r *Request) Cookie(cookie *http.Cookie) *Request {
+	r.cookies = append(r.cookies, cookie)
+	return r
+}
+
+// Body sets request body with content type.
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.headers.Set(web.HeaderContentType, contentType)
+	r.body = body
+	return r
+}
+
+// JSON sets request body with JSON encoded value.
+func (r *Request) JSON(v interface{}) *Request {
+	b, err := json.Marshal(v)
+	assert.NoError(r.c.tb, err)
+	return r.Body(web.MIMEApplicationJSONCharsetUTF8, b)
+}
+
+// Form sets request body with URL encoded form.
+func (r *Request) Form(values url.Values) *Request {
+	return r.Body(web.MIMEApplicationForm, []byte(values.Encode()))
+}
+
+// Do sends request to server and returns the response.
+func (r *Request) Do() *Response {
+	r.c.tb.Helper()
+
+	u, err := r.c.base.Parse(r.path)
+	assert.NoError(r.c.tb, err)
+	if len(r.query) > 0 {
+		q := u.Query()
+		for k, vs := range r.query {
+			q[k] = append(q[k], vs...)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req := httptest.NewRequest(r.method, u.String(), body)
+	req.Header = r.headers
+	for _, cookie := range r.c.jar.Cookies(u) {
+		req.AddCookie(cookie)
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	if r.c.user != nil {
+		req = req.WithContext(context.WithValue(req.Context(), userKey{}, r.c.user))
+	}
+
+	rec := httptest.NewRecorder()
+	r.c.server.ServeHTTP(rec, req)
+	res := rec.Result()
+	if cookies := res.Cookies(); len(cookies) > 0 {
+		r.c.jar.SetCookies(u, cookies)
+	}
+	return &Response{tb: r.c.tb, Response: res, body: rec.Body.Bytes()}
+}
+
+// setUser sets user carried by requests of As before other filters are executed.
+func setUser(next web.HandlerFunc) web.HandlerFunc {
+	return func(ctx web.Context) error {
+		if u, ok := ctx.Request().Context().Value(userKey{}).(web.User); ok {
+			ctx.SetUser(u)
+		}
+		return next(ctx)
+	}
+}
+
+// Response wraps `*http.Response` with assertions, assertions fail test immediately.
+type Response struct {
+	*http.Response
+	tb   testing.TB
+	body []byte
+	data interface{}
+}
+
+// Text returns response body as string.
+func (r *Response) Text() string {
+	return string(r.body)
+}
+
+// Bytes returns response body.
+func (r *Response) Bytes() []byte {
+	return r.body
+}
+
+// Decode decodes JSON body to v.
+func (r *Response) Decode(v interface{}) *Response {
+	r.tb.Helper()
+	assert.NoError(r.tb, json.Unmarshal(r.body, v), "decode response body")
+	return r
+}
+
+// Status asserts status code of response.
+func (r *Response) Status(code int) *Response {
+	r.tb.Helper()
+	assert.Equal(r.tb, code, r.StatusCode, "unexpected status, body: %s", r.body)
+	return r
+}
+
+// HasHeader asserts header of response.
+func (r *Response) HasHeader(key, value string) *Response {
+	r.tb.Helper()
+	assert.Equal(r.tb, value, r.Header.Get(key), "unexpected header: %s", key)
+	return r
+}
+
+// HasBody asserts response body.
+func (r *Response) HasBody(body string) *Response {
+	r.tb.Helper()
+	assert.Equal(r.tb, body, string(r.body))
+	return r
+}
+
+// Contains asserts response body contains s.
+func (r *Response) Contains(s string) *Response {
+	r.tb.Helper()
+	assert.Contains(r.tb, string(r.body), s)
+	return r
+}
+
+// JSON asserts the value at path of JSON body, path is like `data.items[0].name` or `data.items.0.name`.
+// Expected value is compared after encoding to JSON, so `1` equals to JSON number `1.0`.
+func (r *Response) JSON(path string, expected interface{}) *Response {
+	r.tb.Helper()
+
+	if r.data == nil {
+		r.Decode(&r.data)
+	}
+	actual, ok := lookup(r.data, path)
+	assert.True(r.tb, ok, "JSON path not found: %s", path)
+
+	b, err := json.Marshal(expected)
+	assert.NoError(r.tb, err)
+	var v interface{}
+	assert.NoError(r.tb, json.Unmarshal(b, &v))
+	assert.Equal(r.tb, v, actual, "unexpected value at JSON path: %s", path)
+	return r
+}
+
+func lookup(data interface{}, path string) (interface{}, bool) {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+
+		switch v := data.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if data, ok = v[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			data = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return data, true
+}
@@ -0,0 +1,49 @@
+package webtest
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cuigh/auxo/net/web"
+	"github.com/cuigh/auxo/net/web/filter"
+	"github.com/cuigh/auxo/security"
+)
+
+func TestClient(t *testing.T) {
+	s := web.Default()
+	s.Use(&filter.CORS{}, filter.NewAuthorizer(func(user web.User, handler web.HandlerInfo) bool { return true }))
+	s.Post("/login", func(ctx web.Context) error {
+		ctx.SetCookie(&http.Cookie{Name: "name", Value: ctx.F("name"), Path: "/"})
+		return ctx.Redirect("/")
+	}, web.WithAuthorize(web.AuthAnonymous))
+	s.Get("/me", func(ctx web.Context) error {
+		c, err := ctx.Cookie("name")
+		if err != nil {
+			return err
+		}
+		return ctx.JSON(map[string]interface{}{"name": c.Value, "tags": []string{ctx.Q("tag")}})
+	}, web.WithAuthorize(web.AuthAnonymous))
+	s.Post("/echo", func(ctx web.Context) error {
+		m := map[string]interface{}{}
+		if err := ctx.Bind(&m); err != nil {
+			return err
+		}
+		m["user"] = ctx.User().Name()
+		return ctx.JSON(m)
+	}, web.WithAuthorize(web.AuthAuthenticated))
+
+	c := New(t, s)
+	c.Post("/login").Form(url.Values{"name": {"admin"}}).Do().Status(http.StatusFound)
+	c.Get("/me").Query("tag", "x").Header("Origin", "http://a.com").Do().
+		Status(http.StatusOK).
+		HasHeader(web.HeaderAccessControlAllowOrigin, "*").
+		JSON("name", "admin").
+		JSON("tags[0]", "x")
+
+	c.Post("/echo").JSON(map[string]int{"id": 1}).Do().Status(http.StatusUnauthorized)
+	c.As(security.NewUser("1", "bob")).Post("/echo").JSON(map[string]int{"id": 1}).Do().
+		Status(http.StatusOK).
+		JSON("id", 1).
+		JSON("user", "bob")
+}
@@ -0,0 +1,379 @@
+// Package rbac implements a role-based authorization engine which is shared by web and rpc.
+//
+// A policy consists of roles, each role grants permissions and can inherit other roles:
+//
+//	rbac:
+//	  roles:
+//	    - name: viewer
+//	      permissions: [order.read, product.*]
+//	    - name: editor
+//	      inherits: [viewer]
+//	      permissions: [order.*, "!order.delete"]
+//	    - name: admin
+//	      permissions: ["*"]
+//
+// Permissions are dot-separated resources. In patterns, `*` matches one segment, and a trailing `*`
+// matches all remaining segments. A permission prefixed with `!` denies access, deny rules take
+// precedence over allow rules.
+//
+// Handlers declare requirements with authorize rules:
+//
+//	"*"                   anonymous access
+//	"?"                   authenticated users
+//	"!"                   administrators (users having the admin role)
+//	"perm:order.write"    users having all the permissions, comma separated
+//	"role:editor,admin"   users having any of the roles, comma separated
+//
+// Access is denied if a handler declares no rule, unless Options.DefaultRule is set.
+package rbac
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cuigh/auxo/config"
+	"github.com/cuigh/auxo/errors"
+	"github.com/cuigh/auxo/security"
+)
+
+const PkgName = "auxo.security.rbac"
+
+const (
+	RuleAnonymous     = "*"
+	RuleAuthenticated = "?"
+	RuleAdministrator = "!"
+	PrefixPermission  = "perm:"
+	PrefixRole        = "role:"
+)
+
+// Role is a named group of permissions.
+type Role struct {
+	Name        string   `json:"name" yaml:"name"`
+	Inherits    []string `json:"inherits" yaml:"inherits"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+}
+
+// Policy is the set of roles.
+type Policy struct {
+	Roles []Role `json:"roles" yaml:"roles"`
+}
+
+// Store loads policy from an external source like database.
+type Store interface {
+	Load() (*Policy, error)
+}
+
+// StoreFunc is an adapter to allow the use of ordinary functions as Store.
+type StoreFunc func() (*Policy, error)
+
+// Load implements Store interface.
+func (f StoreFunc) Load() (*Policy, error) {
+	return f()
+}
+
+// Config returns a Store which loads policy from config with the key, like `rbac`.
+func Config(key string) Store {
+	return StoreFunc(func() (*Policy, error) {
+		p := &Policy{}
+		if err := config.UnmarshalOption(key, p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+}
+
+// RoleResolver resolves roles of a user.
+type RoleResolver interface {
+	Resolve(user security.User) ([]string, error)
+}
+
+// RoleResolverFunc is an adapter to allow the use of ordinary functions as RoleResolver.
+type RoleResolverFunc func(user security.User) ([]string, error)
+
+// Resolve implements RoleResolver interface.
+func (f RoleResolverFunc) Resolve(user security.User) ([]string, error) {
+	return f(user)
+}
+
+// UserRoles is the default RoleResolver, it returns roles of users which implement `Roles() []string`.
+var UserRoles = RoleResolverFunc(func(user security.User) ([]string, error) {
+	if u, ok := user.(interface{ Roles() []string }); ok {
+		return u.Roles(), nil
+	}
+	return nil, nil
+})
+
+// Decision is the result of an authorization, it explains which rule granted or denied the request.
+type Decision struct {
+	// Allowed indicates whether the request is granted.
+	Allowed bool
+	// Authenticated indicates whether the user is logged in.
+	Authenticated bool
+	// Rule is the authorize rule being checked, like `perm:order.write`.
+	Rule string
+	// Role is the role whose permission granted or denied the request.
+	Role string
+	// Permission is the matched permission pattern of Role, like `order.*` or `!order.delete`.
+	Permission string
+	// Reason describes the decision.
+	Reason string
+}
+
+func (d *Decision) String() string {
+	if d.Allowed {
+		return "granted: " + d.Reason
+	}
+	return "denied: " + d.Reason
+}
+
+// Options represents options of Engine.
+type Options struct {
+	// Resolver resolves roles of users.
+	// Optional. Default value UserRoles.
+	Resolver RoleResolver
+
+	// AdminRole is the role required by rule `!`.
+	// Optional. Default value "admin".
+	AdminRole string
+
+	// DefaultRule is the rule checked when a handler declares no authorize rule, like "?".
+	// Optional. Default value "", which denies access.
+	DefaultRule string
+}
+
+// Engine authorizes users with policy, it is safe for concurrent use and policy can be reloaded.
+type Engine struct {
+	opts  Options
+	roles atomic.Value // map[string][]*grant
+}
+
+type grant struct {
+	role    string // role declaring the permission
+	pattern string // original permission, like `!order.*`
+	deny    bool
+	parts   []string
+}
+
+// New creates an Engine with policy.
+func New(p *Policy, opts ...Options) (*Engine, error) {
+	e := &Engine{}
+	if len(opts) > 0 {
+		e.opts = opts[0]
+	}
+	if e.opts.Resolver == nil {
+		e.opts.Resolver = UserRoles
+	}
+	if e.opts.AdminRole == "" {
+		e.opts.AdminRole = "admin"
+	}
+	if err := e.Load(p); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Load replaces policy of engine, the current policy is kept if p is invalid.
+func (e *Engine) Load(p *Policy) error {
+	roles := make(map[string]*Role)
+	if p != nil {
+		for i := range p.Roles {
+			r := &p.Roles[i]
+			if r.Name == "" {
+				return errors.New("rbac: role name is empty")
+			}
+			if _, ok := roles[r.Name]; ok {
+				return errors.Format("rbac: duplicate role: %s", r.Name)
+			}
+			roles[r.Name] = r
+		}
+	}
+
+	grants := make(map[string][]*grant, len(roles))
+	for name := range roles {
+		gs, err := flatten(roles, name, nil)
+		if err != nil {
+			return err
+		}
+		grants[name] = gs
+	}
+	e.roles.Store(grants)
+	return nil
+}
+
+// Reload loads policy from the store.
+func (e *Engine) Reload(s Store) error {
+	p, err := s.Load()
+	if err != nil {
+		return err
+	}
+	return e.Load(p)
+}
+
+// Authorize reports whether user satisfies the authorize rule.
+func (e *Engine) Authorize(user security.User, rule string) bool {
+	return e.Explain(user, rule).Allowed
+}
+
+// Explain checks the authorize rule and returns the decision with details.
+func (e *Engine) Explain(user security.User, rule string) *Decision {
+	if rule == "" {
+		rule = e.opts.DefaultRule
+	}
+	d := &Decision{Rule: rule, Authenticated: user != nil && !user.Anonymous()}
+	if rule == "" {
+		d.Reason = "no authorize rule is specified"
+		return d
+	}
+	if rule == RuleAnonymous {
+		d.Allowed, d.Reason = true, "anonymous access is allowed"
+		return d
+	}
+	if !d.Authenticated {
+		d.Reason = "user is not authenticated"
+		return d
+	}
+	if rule == RuleAuthenticated {
+		d.Allowed, d.Reason = true, "user is authenticated"
+		return d
+	}
+
+	roles, err := e.opts.Resolver.Resolve(user)
+	if err != nil {
+		d.Reason = "failed to resolve roles: " + err.Error()
+		return d
+	}
+
+	switch {
+	case rule == RuleAdministrator:
+		e.checkRoles(d, roles, []string{e.opts.AdminRole})
+	case strings.HasPrefix(rule, PrefixRole):
+		e.checkRoles(d, roles, split(rule[len(PrefixRole):]))
+	case strings.HasPrefix(rule, PrefixPermission):
+		perms := split(rule[len(PrefixPermission):])
+		if len(perms) == 0 {
+			d.Reason = "no permission is specified"
+			return d
+		}
+		for _, perm := range perms {
+			if e.checkPermission(d, roles, perm); !d.Allowed {
+				break
+			}
+		}
+	default:
+		d.Reason = "unsupported authorize rule"
+	}
+	return d
+}
+
+// Permissions returns effective permission patterns of roles, including inherited ones.
+func (e *Engine) Permissions(roles ...string) []string {
+	grants := e.roles.Load().(map[string][]*grant)
+	var perms []string
+	for _, role := range roles {
+		for _, g := range grants[role] {
+			perms = append(perms, g.pattern)
+		}
+	}
+	return perms
+}
+
+func (e *Engine) checkRoles(d *Decision, roles, required []string) {
+	for _, r := range required {
+		for _, role := range roles {
+			if role == r {
+				d.Allowed, d.Role, d.Reason = true, role, fmt.Sprintf("user has role %s", role)
+				return
+			}
+		}
+	}
+	d.Reason = fmt.Sprintf("user has none of roles %s", strings.Join(required, ","))
+}
+
+func (e *Engine) checkPermission(d *Decision, roles []string, perm string) {
+	grants := e.roles.Load().(map[string][]*grant)
+	parts := strings.Split(perm, ".")
+
+	var allow *grant
+	for _, role := range roles {
+		for _, g := range grants[role] {
+			if !match(g.parts, parts) {
+				continue
+			}
+			if g.deny {
+				d.Allowed, d.Role, d.Permission = false, g.role, g.pattern
+				d.Reason = fmt.Sprintf("permission %s is denied by %s of role %s", perm, g.pattern, g.role)
+				return
+			}
+			if allow == nil {
+				allow = g
+			}
+		}
+	}
+
+	if allow == nil {
+		d.Allowed, d.Role, d.Permission = false, "", ""
+		d.Reason = fmt.Sprintf("no role grants permission %s", perm)
+		return
+	}
+	d.Allowed, d.Role, d.Permission = true, allow.role, allow.pattern
+	d.Reason = fmt.Sprintf("permission %s is granted by %s of role %s", perm, allow.pattern, allow.role)
+}
+
+// flatten collects permissions of role and its ancestors, path is used to detect circular inheritance.
+func flatten(roles map[string]*Role, name string, path []string) ([]*grant, error) {
+	for _, p := range path {
+		if p == name {
+			return nil, errors.Format("rbac: circular inheritance: %s", strings.Join(append(path, name), " -> "))
+		}
+	}
+
+	r, ok := roles[name]
+	if !ok {
+		return nil, errors.Format("rbac: role %s inherits unknown role %s", path[len(path)-1], name)
+	}
+
+	var grants []*grant
+	for _, perm := range r.Permissions {
+		g := &grant{role: name, pattern: perm}
+		if strings.HasPrefix(perm, "!") {
+			g.deny, perm = true, perm[1:]
+		}
+		if perm == "" {
+			return nil, errors.Format("rbac: role %s has empty permission", name)
+		}
+		g.parts = strings.Split(perm, ".")
+		grants = append(grants, g)
+	}
+	for _, parent := range r.Inherits {
+		gs, err := flatten(roles, parent, append(path, name))
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, gs...)
+	}
+	return grants, nil
+}
+
+// match reports whether permission parts match pattern parts.
+func match(pattern, parts []string) bool {
+	for i, p := range pattern {
+		if p == "*" && i == len(pattern)-1 {
+			return len(parts) > i
+		}
+		if i >= len(parts) || (p != "*" && p != parts[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(parts)
+}
+
+func split(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
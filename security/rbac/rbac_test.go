@@ -0,0 +1,92 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/cuigh/auxo/security"
+	"github.com/cuigh/auxo/test/assert"
+)
+
+type user struct {
+	security.User
+	roles []string
+}
+
+func (u *user) Roles() []string {
+	return u.roles
+}
+
+func newUser(roles ...string) security.User {
+	return &user{User: security.NewUser("1", "test"), roles: roles}
+}
+
+func TestEngine(t *testing.T) {
+	e, err := New(&Policy{Roles: []Role{
+		{Name: "viewer", Permissions: []string{"order.read", "product.*"}},
+		{Name: "editor", Inherits: []string{"viewer"}, Permissions: []string{"order.*", "!order.delete"}},
+		{Name: "admin", Permissions: []string{"*"}},
+	}})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		User    security.User
+		Rule    string
+		Allowed bool
+	}{
+		{nil, "*", true},
+		{nil, "", false},
+		{newUser("admin"), "", false},
+		{nil, "?", false},
+		{security.Anonymous, "perm:order.read", false},
+		{newUser(), "?", true},
+		{newUser(), "perm:order.read", false},
+		{newUser("viewer"), "perm:order.read", true},
+		{newUser("viewer"), "perm:order.write", false},
+		{newUser("viewer"), "perm:product.sku.read", true},
+		{newUser("viewer"), "perm:product", false},
+		{newUser("editor"), "perm:order.write,product.read", true},
+		{newUser("editor"), "perm:order.delete", false},
+		{newUser("editor", "admin"), "perm:order.delete", false},
+		{newUser("admin"), "perm:order.delete", true},
+		{newUser("admin"), "!", true},
+		{newUser("editor"), "!", false},
+		{newUser("editor"), "role:viewer,editor", true},
+		{newUser("editor"), "unknown", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.Allowed, e.Authorize(c.User, c.Rule), c.Rule, c.User)
+	}
+
+	d := e.Explain(newUser("editor"), "perm:order.delete")
+	assert.Equal(t, "editor", d.Role)
+	assert.Equal(t, "!order.delete", d.Permission)
+	d = e.Explain(newUser("editor"), "perm:order.read")
+	assert.True(t, d.Allowed)
+	assert.Equal(t, "editor", d.Role)
+	assert.Equal(t, "order.*", d.Permission)
+}
+
+func TestEngine_DefaultRule(t *testing.T) {
+	e, err := New(nil, Options{DefaultRule: RuleAuthenticated})
+	assert.NoError(t, err)
+	assert.False(t, e.Authorize(nil, ""))
+	assert.True(t, e.Authorize(newUser(), ""))
+	assert.Equal(t, RuleAuthenticated, e.Explain(newUser(), "").Rule)
+}
+
+func TestLoad(t *testing.T) {
+	e, err := New(nil)
+	assert.NoError(t, err)
+	assert.False(t, e.Authorize(newUser("a"), "perm:x"))
+
+	assert.Error(t, e.Load(&Policy{Roles: []Role{{Name: "a", Inherits: []string{"b"}}, {Name: "b", Inherits: []string{"a"}}}}))
+	assert.Error(t, e.Load(&Policy{Roles: []Role{{Name: "a", Inherits: []string{"missing"}}}}))
+	assert.Error(t, e.Load(&Policy{Roles: []Role{{Name: "a"}, {Name: "a"}}}))
+
+	err = e.Reload(StoreFunc(func() (*Policy, error) {
+		return &Policy{Roles: []Role{{Name: "a", Permissions: []string{"x"}}}}, nil
+	}))
+	assert.NoError(t, err)
+	assert.True(t, e.Authorize(newUser("a"), "perm:x"))
+	assert.Equal(t, []string{"x"}, e.Permissions("a"))
+}